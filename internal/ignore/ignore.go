@@ -0,0 +1,250 @@
+// Package ignore implements the exclusion rules shared by the modules that
+// walk directories. Rules use the gitignore syntax and come from three
+// places, later ones taking precedence: the built-in defaults, the global
+// file in the config directory and a .ordiignore file in any directory,
+// which applies to everything below it.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the name of the per-directory ignore file.
+const FileName = ".ordiignore"
+
+// Defaults keeps version control data, dependency and build caches and
+// system folders out of every scan.
+var Defaults = []string{
+	FileName,
+	".git/",
+	".hg/",
+	".svn/",
+	".bzr/",
+	"_darcs/",
+	"node_modules/",
+	"bower_components/",
+	"__pycache__/",
+	".venv/",
+	".tox/",
+	".gradle/",
+	".mypy_cache/",
+	".pytest_cache/",
+	".cache/",
+	".Trash/",
+	".Trash-*/",
+	"$RECYCLE.BIN/",
+	"System Volume Information/",
+	".Spotlight-V100/",
+	".fseventsd/",
+}
+
+type rule struct {
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// Matcher decides whether a path is excluded.
+type Matcher struct {
+	rules []rule
+}
+
+// GlobalPath returns the location of the global ignore file.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ordi", "ignore"), nil
+}
+
+// Target returns the ignore file new patterns are written to: the global
+// file or the .ordiignore of dir.
+func Target(dir string, global bool) (string, error) {
+	if global {
+		return GlobalPath()
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// New returns a matcher for a scan of root with the defaults, the global
+// rules and the .ordiignore file of root itself. Files of subfolders are
+// added with ReadDir while walking down.
+func New(root string) (*Matcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Matcher{}
+	for _, pattern := range Defaults {
+		m.add(root, pattern)
+	}
+
+	if global, err := GlobalPath(); err == nil {
+		if err := m.readFile(root, global); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.ReadDir(root); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadDir adds the rules of the .ordiignore file in dir, if there is one.
+func (m *Matcher) ReadDir(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return m.readFile(dir, filepath.Join(dir, FileName))
+}
+
+func (m *Matcher) readFile(base, file string) error {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if err := m.add(base, scanner.Text()); err != nil {
+			return fmt.Errorf("%s, Zeile %d: %w", file, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// add parses one line in gitignore syntax. Patterns without a slash match
+// at any depth; patterns with one are relative to base.
+func (m *Matcher) add(base, line string) error {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	r.segments = strings.Split(line, "/")
+	if !anchored {
+		r.segments = append([]string{"**"}, r.segments...)
+	}
+	for _, segment := range r.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("ungültiges Muster %q", line)
+		}
+	}
+
+	m.rules = append(m.rules, r)
+	return nil
+}
+
+// Match reports whether the file or directory at path is excluded. The last
+// matching rule decides, so "!" patterns can re-include entries.
+func (m *Matcher) Match(filePath string, isDir bool) bool {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return false
+	}
+
+	excluded := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(r.base, abs)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if matchSegments(r.segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// Append adds pattern to the ignore file at file, creating it if needed.
+func Append(file, pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return fmt.Errorf("Das Muster ist leer.")
+	}
+	if err := (&Matcher{}).add("", pattern); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	// Make sure the pattern starts on a line of its own.
+	prefix := ""
+	if data, err := os.ReadFile(file); err == nil && len(data) > 0 && data[len(data)-1] != '\n' {
+		prefix = "\n"
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(prefix + pattern + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,215 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTempConfig keeps the global ignore file of the user out of the tests.
+func useTempConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+}
+
+func writeIgnore(t *testing.T, dir, rules string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type check struct {
+	path  string
+	isDir bool
+	want  bool
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  string
+		checks []check
+	}{
+		{
+			name:  "Ausnahme nach Regel",
+			rules: "*.log\n!wichtig.log\n",
+			checks: []check{
+				{path: "fehler.log", want: true},
+				{path: "wichtig.log", want: false},
+				{path: "sub/wichtig.log", want: false},
+			},
+		},
+		{
+			name:  "Ausnahme vor Regel",
+			rules: "!wichtig.log\n*.log\n",
+			checks: []check{
+				{path: "wichtig.log", want: true},
+			},
+		},
+		{
+			name:  "nicht verankert",
+			rules: "*.tmp\nbuild\n",
+			checks: []check{
+				{path: "a.tmp", want: true},
+				{path: "sub/tief/a.tmp", want: true},
+				{path: "sub/build", isDir: true, want: true},
+				{path: "a.tmp.txt", want: false},
+			},
+		},
+		{
+			name:  "verankert",
+			rules: "/build\ndocs/*.md\n",
+			checks: []check{
+				{path: "build", isDir: true, want: true},
+				{path: "sub/build", isDir: true, want: false},
+				{path: "docs/a.md", want: true},
+				{path: "docs/sub/a.md", want: false},
+				{path: "x/docs/a.md", want: false},
+			},
+		},
+		{
+			name:  "nur Ordner",
+			rules: "cache/\n",
+			checks: []check{
+				{path: "cache", isDir: true, want: true},
+				{path: "sub/cache", isDir: true, want: true},
+				{path: "cache", want: false},
+			},
+		},
+		{
+			name:  "maskierte Zeichen",
+			rules: "\\!wichtig.txt\n\\#notiz.txt\n# Kommentar\n",
+			checks: []check{
+				{path: "!wichtig.txt", want: true},
+				{path: "wichtig.txt", want: false},
+				{path: "#notiz.txt", want: true},
+				{path: "# Kommentar", want: false},
+			},
+		},
+		{
+			name:  "Doppelstern",
+			rules: "a/**/b\nlogs/**\n**/tmp/*.bak\n",
+			checks: []check{
+				{path: "a/b", want: true},
+				{path: "a/x/y/b", want: true},
+				{path: "x/a/b", want: false},
+				{path: "logs/heute.txt", want: true},
+				{path: "logs/2024/heute.txt", want: true},
+				{path: "logs", isDir: true, want: false},
+				{path: "tmp/x.bak", want: true},
+				{path: "sub/tmp/x.bak", want: true},
+				{path: "sub/tmp/x.txt", want: false},
+			},
+		},
+		{
+			name: "Standardregeln",
+			checks: []check{
+				{path: ".git", isDir: true, want: true},
+				{path: "projekt/node_modules", isDir: true, want: true},
+				{path: FileName, want: true},
+				{path: ".Trash-1000", isDir: true, want: true},
+				{path: "node_modules", want: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			root := t.TempDir()
+			if tt.rules != "" {
+				writeIgnore(t, root, tt.rules)
+			}
+			m, err := New(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range tt.checks {
+				if got := m.Match(filepath.Join(root, filepath.FromSlash(c.path)), c.isDir); got != c.want {
+					t.Errorf("Match(%q, %v) = %v, erwartet %v", c.path, c.isDir, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchNested(t *testing.T) {
+	useTempConfig(t)
+	root := t.TempDir()
+	writeIgnore(t, root, "*.log\n")
+	writeIgnore(t, filepath.Join(root, "a"), "*.bak\n!wichtig.log\n/nur-hier.txt\n")
+	if err := os.MkdirAll(filepath.Join(root, "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The walk reads a before its sibling b; the rules of a must not reach b.
+	for _, dir := range []string{"a", "b"} {
+		if err := m.ReadDir(filepath.Join(root, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checks := []check{
+		{path: "a/x.bak", want: true},
+		{path: "a/sub/x.bak", want: true},
+		{path: "a/wichtig.log", want: false},
+		{path: "a/nur-hier.txt", want: true},
+		{path: "a/sub/nur-hier.txt", want: false},
+		{path: "b/x.bak", want: false},
+		{path: "b/wichtig.log", want: true},
+		{path: "b/nur-hier.txt", want: false},
+		{path: "x.bak", want: false},
+		{path: "wichtig.log", want: true},
+	}
+	for _, c := range checks {
+		if got := m.Match(filepath.Join(root, filepath.FromSlash(c.path)), c.isDir); got != c.want {
+			t.Errorf("Match(%q) = %v, erwartet %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestGlobalRules(t *testing.T) {
+	useTempConfig(t)
+	global, err := GlobalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(global, "*.iso"); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	writeIgnore(t, root, "!behalten.iso\n")
+
+	m, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Match(filepath.Join(root, "image.iso"), false) {
+		t.Error("globale Regel nicht angewendet")
+	}
+	if m.Match(filepath.Join(root, "behalten.iso"), false) {
+		t.Error(".ordiignore geht der globalen Datei nicht vor")
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	useTempConfig(t)
+	root := t.TempDir()
+	writeIgnore(t, root, "*.log\n[abc\n")
+	if _, err := New(root); err == nil {
+		t.Error("ungültiges Muster nicht gemeldet")
+	}
+	if err := Append(filepath.Join(root, "neu"), "[abc"); err == nil {
+		t.Error("Append nahm ein ungültiges Muster an")
+	}
+}
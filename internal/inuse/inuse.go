@@ -0,0 +1,114 @@
+// Package inuse recognises files that are still being written, so the
+// modules that move or delete files can leave them alone. A file counts as
+// in use if its name marks an unfinished download, if its size or
+// modification time still changes, or, on Linux, if a process holds it open
+// for writing.
+package inuse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// partialSuffixes mark downloads and copies that are still in progress.
+var partialSuffixes = []string{".part", ".crdownload", ".tmp", ".download", ".partial", ".opdownload", ".!qb", ".aria2"}
+
+const (
+	// Window is how long a recently modified file is observed before it
+	// counts as finished.
+	Window = 2 * time.Second
+	// recent is the age below which a file gets observed at all; anything
+	// older has not been written to for a while.
+	recent = time.Minute
+)
+
+// File is a file that was left alone, with the reason why.
+type File struct {
+	Path   string
+	Reason string
+}
+
+// IsPartial reports whether name marks an unfinished download.
+func IsPartial(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checker tells whether single files are in use. It holds a snapshot of the
+// open write handles taken when it was created, so it should be used for
+// one run only.
+type Checker struct {
+	writers map[string]string
+}
+
+func NewChecker() *Checker {
+	return &Checker{writers: openWriters()}
+}
+
+// Busy checks path by its name and the open handles and returns the reason
+// if it is in use.
+func (c *Checker) Busy(path string) (string, bool) {
+	if IsPartial(filepath.Base(path)) {
+		return "unfertiger Download", true
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		if process, ok := c.writers[abs]; ok {
+			return "wird von " + process + " geschrieben", true
+		}
+	}
+	return "", false
+}
+
+// Changed reports whether the file at path differs from what was seen
+// earlier, which means it was written to in the meantime.
+func Changed(path string, size int64, modTime time.Time) bool {
+	info, err := os.Stat(path)
+	return err == nil && (info.Size() != size || !info.ModTime().Equal(modTime))
+}
+
+// Check returns the files of paths that are in use. Files modified within
+// the last minute are observed for Window, so Check may block that long.
+func Check(paths []string) []File {
+	checker := NewChecker()
+
+	var busy []File
+	observed := make(map[string]os.FileInfo)
+	for _, path := range paths {
+		if reason, ok := checker.Busy(path); ok {
+			busy = append(busy, File{Path: path, Reason: reason})
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && time.Since(info.ModTime()) < recent {
+			observed[path] = info
+		}
+	}
+	if len(observed) == 0 {
+		return busy
+	}
+
+	time.Sleep(Window)
+	for _, path := range paths {
+		info, ok := observed[path]
+		if ok && Changed(path, info.Size(), info.ModTime()) {
+			busy = append(busy, File{Path: path, Reason: "wird noch geschrieben"})
+		}
+	}
+	return busy
+}
+
+// Set returns the paths of files for quick lookups.
+func Set(files []File) map[string]bool {
+	set := make(map[string]bool, len(files))
+	for _, f := range files {
+		set[f.Path] = true
+	}
+	return set
+}
@@ -0,0 +1,77 @@
+package inuse
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// openWriters lists the files other processes hold open for writing, with
+// the name of the process. Only processes of the current user can be
+// inspected; the others are skipped.
+func openWriters() map[string]string {
+	writers := make(map[string]string)
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return writers
+	}
+	self := os.Getpid()
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join("/proc", proc.Name())
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !filepath.IsAbs(target) {
+				// Sockets, pipes and the like.
+				continue
+			}
+			if _, seen := writers[target]; seen || !writable(filepath.Join(dir, "fdinfo", fd.Name())) {
+				continue
+			}
+			writers[target] = processName(dir)
+		}
+	}
+	return writers
+}
+
+// writable reads the open flags of a file descriptor from its fdinfo file.
+func writable(fdinfo string) bool {
+	f, err := os.Open(fdinfo)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return false
+		}
+		return flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	}
+	return false
+}
+
+func processName(dir string) string {
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil || len(strings.TrimSpace(string(comm))) == 0 {
+		return "Prozess " + filepath.Base(dir)
+	}
+	return strings.TrimSpace(string(comm))
+}
@@ -0,0 +1,133 @@
+package inuse
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsPartial(t *testing.T) {
+	tests := map[string]bool{
+		"film.mkv.part":        true,
+		"FILM.MKV.PART":        true,
+		"setup.exe.crdownload": true,
+		"Bericht.PDF.Download": true,
+		"daten.tmp":            true,
+		"iso.aria2":            true,
+		"album.zip.!qB":        true,
+		"film.mkv":             false,
+		"part.txt":             false,
+		"tmp":                  false,
+		"partial.jpg":          false,
+	}
+	for name, want := range tests {
+		if got := IsPartial(name); got != want {
+			t.Errorf("IsPartial(%q) = %v, erwartet %v", name, got, want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeFile(t, path, "inhalt", modTime)
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int64
+		modTime time.Time
+		want    bool
+	}{
+		{name: "unverändert", path: path, size: 6, modTime: modTime},
+		{name: "andere Größe", path: path, size: 3, modTime: modTime, want: true},
+		{name: "andere Zeit", path: path, size: 6, modTime: modTime.Add(time.Second), want: true},
+		{name: "verschwunden", path: filepath.Join(dir, "fehlt.txt"), size: 6, modTime: modTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Changed(tt.path, tt.size, tt.modTime); got != tt.want {
+				t.Errorf("Changed = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	paths := map[string]string{
+		"fertig":   filepath.Join(dir, "fertig.pdf"),
+		"download": filepath.Join(dir, "film.mkv.part"),
+		"ruhig":    filepath.Join(dir, "ruhig.txt"),
+		"schreibt": filepath.Join(dir, "schreibt.log"),
+	}
+	writeFile(t, paths["fertig"], "fertig", old)
+	writeFile(t, paths["download"], "halb", old)
+	writeFile(t, paths["ruhig"], "ruhig", time.Now())
+	writeFile(t, paths["schreibt"], "anfang", time.Now())
+
+	// Append to one of the recent files while Check observes them.
+	done := make(chan error, 1)
+	go func() {
+		time.Sleep(Window / 4)
+		f, err := os.OpenFile(paths["schreibt"], os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			done <- err
+			return
+		}
+		_, err = f.WriteString(" und mehr")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		done <- err
+	}()
+
+	start := time.Now()
+	busy := Check([]string{paths["fertig"], paths["download"], paths["ruhig"], paths["schreibt"]})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < Window {
+		t.Errorf("Check kehrte nach %v zurück, erwartet mindestens %v", elapsed, Window)
+	}
+
+	want := []File{
+		{Path: paths["download"], Reason: "unfertiger Download"},
+		{Path: paths["schreibt"], Reason: "wird noch geschrieben"},
+	}
+	if !reflect.DeepEqual(busy, want) {
+		t.Errorf("Check = %+v, erwartet %+v", busy, want)
+	}
+	set := Set(busy)
+	if !set[paths["schreibt"]] || set[paths["ruhig"]] {
+		t.Errorf("Set = %v", set)
+	}
+}
+
+func TestCheckOldFilesDoNotWait(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "alt.txt")
+	writeFile(t, path, "alt", time.Now().Add(-time.Hour))
+
+	start := time.Now()
+	if busy := Check([]string{path}); len(busy) != 0 {
+		t.Errorf("Check = %+v, erwartet keine", busy)
+	}
+	if elapsed := time.Since(start); elapsed >= Window {
+		t.Errorf("Check wartete %v auf eine alte Datei", elapsed)
+	}
+}
@@ -2,9 +2,13 @@ package compressor
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"example/ordi/internal/sidecar"
 )
 
 
@@ -151,3 +155,37 @@ func compressFile(inputPath, outputPath string) error {
 	}
 	return nil
 }
+
+// copyCompanions gives the compressed file a copy of the sidecars of the
+// original, named after the compressed file: IMG_0042.xmp becomes
+// IMG_0042_compressed.xmp. An existing sidecar there is never overwritten.
+func copyCompanions(inputPath, outputPath string) error {
+	companions, err := sidecar.Of(inputPath)
+	if err != nil {
+		return err
+	}
+	for _, companion := range companions {
+		if err := copyFile(companion, sidecar.Dest(inputPath, companion, outputPath)); err != nil {
+			return fmt.Errorf("Begleitdatei %s konnte nicht kopiert werden: %w", filepath.Base(companion), err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
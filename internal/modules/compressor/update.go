@@ -155,7 +155,11 @@ func compressFilesCmd(files []string, outputDir string) tea.Cmd {
 			}
 
 			
-			if err := compressFile(file, outputPath); err != nil {
+			err := compressFile(file, outputPath)
+			if err == nil {
+				err = copyCompanions(file, outputPath)
+			}
+			if err != nil {
 				failed++
 			} else {
 				success++
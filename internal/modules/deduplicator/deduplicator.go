@@ -3,12 +3,19 @@ package deduplicator
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
+	"syscall"
+
+	"example/ordi/internal/ignore"
+	"example/ordi/internal/inuse"
+	"example/ordi/internal/sidecar"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -17,12 +24,35 @@ import (
 func scanDirectory(dirPath string) tea.Cmd {
 	return func() tea.Msg {
 		var files []string
+		ignored := 0
+
+		matcher, err := ignore.New(dirPath)
+		if err != nil {
+			return ScanCompleteMsg{Err: err}
+		}
 
-		err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
-			if !info.IsDir() && info.Size() > 0 {
+			if info.IsDir() {
+				if path == dirPath {
+					return nil
+				}
+				if matcher.Match(path, true) {
+					ignored++
+					return filepath.SkipDir
+				}
+				return matcher.ReadDir(path)
+			}
+			if info.Name() == ignore.FileName {
+				return nil
+			}
+			if matcher.Match(path, false) {
+				ignored++
+				return nil
+			}
+			if info.Size() > 0 {
 				files = append(files, path)
 			}
 			return nil
@@ -32,12 +62,29 @@ func scanDirectory(dirPath string) tea.Cmd {
 			return ScanCompleteMsg{Err: err}
 		}
 
-		return ScanCompleteMsg{Files: files}
+		// Files that are still being written cannot be compared yet.
+		busy := inuse.Check(files)
+		if len(busy) > 0 {
+			skip := inuse.Set(busy)
+			settled := files[:0]
+			for _, file := range files {
+				if !skip[file] {
+					settled = append(settled, file)
+				}
+			}
+			files = settled
+		}
+
+		// Sidecars are not compared on their own: identical XMP files of
+		// different photos are no duplicates. They go with their primary file.
+		files, companions := sidecar.Split(files)
+
+		return ScanCompleteMsg{Files: files, Companions: companions, Ignored: ignored, Busy: busy}
 	}
 }
 
 
-func findDuplicates(files []string) tea.Cmd {
+func findDuplicates(files []string, companions map[string][]string) tea.Cmd {
 	return func() tea.Msg {
 		
 		sizeGroups := make(map[int64][]string)
@@ -109,8 +156,9 @@ func findDuplicates(files []string) tea.Cmd {
 				continue
 			}
 			hashGroups[result.hash] = append(hashGroups[result.hash], FileInfo{
-				Path: result.path,
-				Size: result.size,
+				Path:       result.path,
+				Size:       result.size,
+				Companions: companions[result.path],
 			})
 		}
 
@@ -184,10 +232,21 @@ func deleteDuplicates(groups []DuplicateGroup) tea.Cmd {
 		deletedCount := 0
 		freedSpace := int64(0)
 		var lastErr error
+		var busy []inuse.File
+		checker := inuse.NewChecker()
 
 		for _, group := range groups {
+			kept := group.Files[0]
 			for _, file := range group.Files {
 				if file.Selected {
+					if reason, ok := checker.Busy(file.Path); ok {
+						busy = append(busy, inuse.File{Path: file.Path, Reason: reason})
+						continue
+					}
+					if info, err := os.Stat(file.Path); err == nil && info.Size() != file.Size {
+						busy = append(busy, inuse.File{Path: file.Path, Reason: "wurde seit dem Scan verändert"})
+						continue
+					}
 					err := os.Remove(file.Path)
 					if err != nil {
 						lastErr = fmt.Errorf("failed to delete %s: %w", file.Path, err)
@@ -195,6 +254,12 @@ func deleteDuplicates(groups []DuplicateGroup) tea.Cmd {
 					}
 					deletedCount++
 					freedSpace += file.Size
+
+					freed, err := dropCompanions(file, kept)
+					freedSpace += freed
+					if err != nil {
+						lastErr = err
+					}
 				}
 			}
 		}
@@ -206,8 +271,115 @@ func deleteDuplicates(groups []DuplicateGroup) tea.Cmd {
 		return DeleteCompleteMsg{
 			DeletedCount: deletedCount,
 			FreedSpace:   freedSpace,
+			Busy:         busy,
 			Err:          lastErr,
 		}
 	}
 }
 
+
+// dropCompanions removes the sidecars of a deleted duplicate that the kept
+// file already has with the same content. Any other sidecar, such as the XMP
+// with the edits of a photo, is moved next to the kept file instead, under a
+// name of its own if the kept file has a different one. It returns the space
+// freed.
+func dropCompanions(file, kept FileInfo) (int64, error) {
+	freed := int64(0)
+	var lastErr error
+	for _, companion := range file.Companions {
+		info, err := os.Stat(companion)
+		if err != nil {
+			continue
+		}
+		dest := sidecar.Dest(file.Path, companion, kept.Path)
+		if _, err := os.Stat(dest); err == nil {
+			same, err := sameContent(companion, dest)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if same {
+				if err := os.Remove(companion); err != nil {
+					lastErr = fmt.Errorf("failed to delete %s: %w", companion, err)
+					continue
+				}
+				freed += info.Size()
+				continue
+			}
+			if dest, err = uniqueName(dest); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		if err := moveFile(companion, dest); err != nil {
+			lastErr = fmt.Errorf("failed to move %s: %w", companion, err)
+		}
+	}
+	return freed, lastErr
+}
+
+// sameContent reports whether two files hold the same bytes.
+func sameContent(a, b string) (bool, error) {
+	hashA, sizeA, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, sizeB, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return sizeA == sizeB && hashA == hashB, nil
+}
+
+// uniqueName appends " (2)", " (3)", ... to the file name until it is free.
+func uniqueName(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// moveFile renames src to dst. Across file systems the file is copied,
+// flushed to disk and only then removed at the source.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return os.Remove(src)
+}
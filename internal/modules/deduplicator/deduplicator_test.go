@@ -0,0 +1,80 @@
+package deduplicator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDropCompanions(t *testing.T) {
+	tests := []struct {
+		name  string
+		kept  map[string]string // sidecars of the kept file by name
+		want  map[string]string // sidecars next to the kept file afterwards
+		freed int64
+	}{
+		{
+			name:  "fehlende Begleitdatei wird verschoben",
+			kept:  map[string]string{},
+			want:  map[string]string{"IMG.xmp": "bearbeitet"},
+			freed: 0,
+		},
+		{
+			name:  "identische Begleitdatei wird gelöscht",
+			kept:  map[string]string{"IMG.xmp": "bearbeitet"},
+			want:  map[string]string{"IMG.xmp": "bearbeitet"},
+			freed: int64(len("bearbeitet")),
+		},
+		{
+			name:  "abweichende Begleitdatei bleibt erhalten",
+			kept:  map[string]string{"IMG.xmp": "original"},
+			want:  map[string]string{"IMG.xmp": "original", "IMG (2).xmp": "bearbeitet"},
+			freed: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			write := func(path, content string) {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kept := FileInfo{Path: filepath.Join(dir, "behalten", "IMG.jpg")}
+			dup := FileInfo{
+				Path:       filepath.Join(dir, "kopie", "Kopie.jpg"),
+				Companions: []string{filepath.Join(dir, "kopie", "Kopie.xmp")},
+			}
+			write(kept.Path, "foto")
+			write(dup.Path, "foto")
+			write(dup.Companions[0], "bearbeitet")
+			for name, content := range tt.kept {
+				write(filepath.Join(dir, "behalten", name), content)
+			}
+
+			freed, err := dropCompanions(dup, kept)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if freed != tt.freed {
+				t.Errorf("%d Bytes freigegeben, erwartet %d", freed, tt.freed)
+			}
+			if _, err := os.Stat(dup.Companions[0]); !os.IsNotExist(err) {
+				t.Errorf("Begleitdatei des Duplikats ist noch da")
+			}
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, "behalten", name))
+				if err != nil {
+					t.Errorf("%s fehlt: %v", name, err)
+					continue
+				}
+				if string(data) != content {
+					t.Errorf("%s enthält %q, erwartet %q", name, data, content)
+				}
+			}
+		})
+	}
+}
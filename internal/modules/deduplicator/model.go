@@ -1,6 +1,8 @@
 package deduplicator
 
 import (
+	"example/ordi/internal/inuse"
+
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -8,8 +10,11 @@ import (
 )
 
 type ScanCompleteMsg struct {
-	Files []string
-	Err   error
+	Files      []string
+	Companions map[string][]string
+	Ignored    int
+	Busy       []inuse.File
+	Err        error
 }
 
 type HashProgressMsg struct {
@@ -28,6 +33,7 @@ type HashCompleteMsg struct {
 type DeleteCompleteMsg struct {
 	DeletedCount int
 	FreedSpace   int64
+	Busy         []inuse.File
 	Err          error
 }
 
@@ -57,9 +63,10 @@ type SimilarGroup struct {
 }
 
 type FileInfo struct {
-	Path     string
-	Size     int64
-	Selected bool // For deletion
+	Path       string
+	Size       int64
+	Selected   bool     // For deletion
+	Companions []string // Sidecars deleted along with the file
 }
 
 type Model struct {
@@ -72,6 +79,13 @@ type Model struct {
 	// Scanning state
 	dirPath       string
 	scannedFiles  []string
+	ignoredCount  int
+	busy          []inuse.File
+
+	// Ignore pattern input
+	ignoreInput   textinput.Model
+	ignoring      bool
+	ignoreGlobal  bool
 
 	// Hashing state
 	hashProgress  int
@@ -106,10 +120,16 @@ func New() Model {
 
 	p := progress.New(progress.WithDefaultGradient())
 
+	ii := textinput.New()
+	ii.Placeholder = "z.B. node_modules/ oder *.tmp"
+	ii.CharLimit = 256
+	ii.Width = 60
+
 	return Model{
-		textInput: ti,
-		spinner:   s,
-		progress:  p,
-		state:     stateInput,
+		textInput:   ti,
+		spinner:     s,
+		progress:    p,
+		state:       stateInput,
+		ignoreInput: ii,
 	}
 }
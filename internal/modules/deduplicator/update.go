@@ -3,6 +3,8 @@ package deduplicator
 import (
 	"fmt"
 
+	"example/ordi/internal/ignore"
+
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
@@ -44,7 +46,45 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case stateResults:
+			if m.ignoring {
+				switch msg.Type {
+				case tea.KeyEnter:
+					target, err := ignore.Target(m.dirPath, m.ignoreGlobal)
+					if err == nil {
+						err = ignore.Append(target, m.ignoreInput.Value())
+					}
+					if err != nil {
+						m.err = err
+						return m, nil
+					}
+					m.ignoring = false
+					m.ignoreInput.Blur()
+					m.err = nil
+					m.state = stateScanning
+					return m, tea.Batch(
+						m.spinner.Tick,
+						scanDirectory(m.dirPath),
+					)
+				case tea.KeyEsc:
+					m.ignoring = false
+					m.ignoreInput.Blur()
+					m.err = nil
+					return m, nil
+				case tea.KeyTab:
+					m.ignoreGlobal = !m.ignoreGlobal
+					return m, nil
+				default:
+					var cmd tea.Cmd
+					m.ignoreInput, cmd = m.ignoreInput.Update(msg)
+					return m, cmd
+				}
+			}
+
 			switch msg.String() {
+			case "i":
+				m.ignoring = true
+				m.ignoreInput.SetValue("")
+				return m, m.ignoreInput.Focus()
 			case "enter":
 				if len(m.duplicates) > 0 {
 					m.state = stateSelection
@@ -111,11 +151,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.scannedFiles = msg.Files
+		m.ignoredCount = msg.Ignored
+		m.busy = msg.Busy
 		m.hashTotal = len(msg.Files)
 		m.hashProgress = 0
 		m.state = stateHashing
 
-		return m, findDuplicates(msg.Files)
+		return m, findDuplicates(msg.Files, msg.Companions)
 
 	case HashProgressMsg:
 		m.hashProgress = msg.Current
@@ -140,6 +182,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.savingsSize = msg.FreedSpace
 		}
+		m.busy = msg.Busy
 		m.state = stateFinished
 		return m, nil
 
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"example/ordi/internal/ignore"
+	"example/ordi/internal/inuse"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -75,6 +78,13 @@ func (m Model) View() string {
 			b.WriteString(successStyle.Render("✓ Keine Duplikate gefunden!"))
 			b.WriteString("\n\n")
 			b.WriteString(fmt.Sprintf("Gescannte Dateien: %d\n", len(m.scannedFiles)))
+			if m.ignoredCount > 0 {
+				b.WriteString(fmt.Sprintf("Ignoriert:         %d Einträge (%s)\n", m.ignoredCount, ignore.FileName))
+			}
+			if len(m.busy) > 0 {
+				b.WriteString(busyList(m.busy))
+				b.WriteString("\n")
+			}
 		} else {
 			stats := []string{
 				fmt.Sprintf("Gescannte Dateien:       %d", len(m.scannedFiles)),
@@ -82,8 +92,15 @@ func (m Model) View() string {
 				fmt.Sprintf("Ähnliche Bilder:         %d Gruppen", len(m.similarImages)),
 				fmt.Sprintf("Verschwendeter Speicher: %s", formatBytes(m.duplicateSize)),
 			}
+			if m.ignoredCount > 0 {
+				stats = append(stats, fmt.Sprintf("Ignoriert:               %d Einträge (%s)", m.ignoredCount, ignore.FileName))
+			}
 			b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, stats...)))
 			b.WriteString("\n\n")
+			if len(m.busy) > 0 {
+				b.WriteString(busyList(m.busy))
+				b.WriteString("\n\n")
+			}
 
 			// Show first few duplicate groups
 			shown := 0
@@ -134,7 +151,18 @@ func (m Model) View() string {
 		}
 
 		b.WriteString("\n")
-		b.WriteString(helpStyle.Render("Enter = Bereinigung starten • Esc = Zurück zum Menü"))
+		if m.ignoring {
+			target := "in " + ignore.FileName + " dieses Ordners"
+			if m.ignoreGlobal {
+				target = "global"
+			}
+			b.WriteString(fmt.Sprintf("Ignorier-Muster (%s):\n", target))
+			b.WriteString(m.ignoreInput.View())
+			b.WriteString("\n")
+			b.WriteString(helpStyle.Render("Enter = Speichern und neu scannen • Tab = Ordner/global • Esc = Abbrechen"))
+			break
+		}
+		b.WriteString(helpStyle.Render("Enter = Bereinigung starten • i = Muster ignorieren • Esc = Zurück zum Menü"))
 
 	case stateSelection:
 		b.WriteString(titleStyle.Render("Duplikate zur Löschung auswählen"))
@@ -170,7 +198,11 @@ func (m Model) View() string {
 					style = style.Foreground(lipgloss.Color("205"))
 				}
 
-				b.WriteString(fmt.Sprintf("%s%s %s\n", cursor, checkbox, style.Render(truncatePath(file.Path, 65))))
+				line := truncatePath(file.Path, 65)
+				if n := len(file.Companions); n > 0 {
+					line += fmt.Sprintf(" (+%d Begleitdateien)", n)
+				}
+				b.WriteString(fmt.Sprintf("%s%s %s\n", cursor, checkbox, style.Render(line)))
 				currentItem++
 			}
 		}
@@ -194,6 +226,10 @@ func (m Model) View() string {
 			b.WriteString(errorStyle.Render(fmt.Sprintf("Fehler: %v", m.err)))
 		} else {
 			b.WriteString(successStyle.Render(fmt.Sprintf("✓ %s Speicher freigegeben!", formatBytes(m.savingsSize))))
+			if len(m.busy) > 0 {
+				b.WriteString("\n\n")
+				b.WriteString(busyList(m.busy))
+			}
 		}
 		b.WriteString("\n\n")
 		b.WriteString(helpStyle.Render("Enter = Zurück zum Menü"))
@@ -222,6 +258,19 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// busyList names the files that were left alone because they are in use.
+func busyList(files []inuse.File) string {
+	lines := []string{fmt.Sprintf("⏳ %d Dateien in Benutzung, nicht angefasst:", len(files))}
+	for i, file := range files {
+		if i >= 5 {
+			lines = append(lines, fmt.Sprintf("  ... und %d weitere", len(files)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %s (%s)", truncatePath(file.Path, 50), file.Reason))
+	}
+	return errorStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
 func truncatePath(path string, maxLen int) string {
 	if len(path) <= maxLen {
 		return path
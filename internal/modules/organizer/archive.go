@@ -0,0 +1,481 @@
+package organizer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example/ordi/internal/inuse"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveFormat is the container stale files are packed into. Both formats
+// are written in pure Go, so no external tools are needed.
+type ArchiveFormat int
+
+const (
+	ArchiveTarZst ArchiveFormat = iota
+	ArchiveZip
+	archiveFormatCount
+)
+
+func (f ArchiveFormat) String() string {
+	if f == ArchiveZip {
+		return "zip"
+	}
+	return "tar.zst"
+}
+
+func (f ArchiveFormat) ext() string {
+	return "." + f.String()
+}
+
+func archiveFormatOf(path string) ArchiveFormat {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return ArchiveZip
+	}
+	return ArchiveTarZst
+}
+
+const (
+	// archiveDirName is the folder below the organized directory that holds
+	// the bundles, one per quarter: Ordi-Archiv/2023-Q4.tar.zst. It differs
+	// from the "Archive" category, so bundles never mix with archive files
+	// sorted there.
+	archiveDirName = "Ordi-Archiv"
+	// manifestName is the index stored as the last entry of every bundle.
+	manifestName = "MANIFEST.json"
+)
+
+// archivePresets are the ages in days selectable on the options screen.
+var archivePresets = []int{30, 90, 180, 365, 730}
+
+// archiveAfter returns the age in days at which files of cat are archived,
+// or 0 if they stay. A category setting overrides the one of the run.
+func (o Options) archiveAfter(cat Category) int {
+	if o.ArchiveAfter <= 0 || o.linking() || cat.ArchiveAfterDays < 0 {
+		return 0
+	}
+	if cat.ArchiveAfterDays > 0 {
+		return cat.ArchiveAfterDays
+	}
+	return o.ArchiveAfter
+}
+
+// archiveBundle returns the bundle a file described by v goes to if it is
+// old enough to be archived.
+func (o Options) archiveBundle(root string, v templateVars) (string, bool) {
+	days := o.archiveAfter(v.category)
+	if days == 0 || time.Since(v.modTime) < time.Duration(days)*24*time.Hour {
+		return "", false
+	}
+	quarter := (int(v.modTime.Month())-1)/3 + 1
+	name := fmt.Sprintf("%d-Q%d%s", v.modTime.Year(), quarter, o.ArchiveFormat.ext())
+	return filepath.Join(root, archiveDirName, name), true
+}
+
+// archiveManifest is the index of a bundle. It lists every file with its
+// original location and checksum.
+type archiveManifest struct {
+	Created time.Time      `json:"created"`
+	Files   []archivedFile `json:"files"`
+}
+
+type archivedFile struct {
+	Name    string      `json:"name"`
+	Source  string      `json:"source"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	SHA256  string      `json:"sha256"`
+}
+
+// defaultFileMode is the permission of files restored from bundles that did
+// not record one.
+const defaultFileMode os.FileMode = 0o644
+
+// archiveFiles packs the stale files of a plan into their bundles. A bundle
+// is written to a temporary file, read back and checked against its
+// manifest, and only then put in place; the originals are removed after
+// that. report, if set, is called before every file.
+func archiveFiles(ctx context.Context, journal *Journal, files []FilePreview, stats *CategoryStats, report func(FilePreview)) error {
+	var order []string
+	bundles := make(map[string][]FilePreview)
+	for _, file := range files {
+		if _, ok := bundles[file.Dest]; !ok {
+			order = append(order, file.Dest)
+		}
+		bundles[file.Dest] = append(bundles[file.Dest], file)
+	}
+
+	for _, dest := range order {
+		if ctx.Err() != nil {
+			return errAborted
+		}
+		if err := archiveBundleFiles(ctx, journal, dest, bundles[dest], stats, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func archiveBundleFiles(ctx context.Context, journal *Journal, dest string, files []FilePreview, stats *CategoryStats, report func(FilePreview)) error {
+	created, err := createDir(filepath.Dir(dest))
+	if err != nil {
+		return err
+	}
+	for _, dir := range created {
+		if err := journal.recordDir(dir); err != nil {
+			return err
+		}
+	}
+
+	// Bundles are never appended to; a second run for the same quarter
+	// writes "2023-Q4 (2).tar.zst".
+	if exists, err := pathExists(dest); err != nil {
+		return err
+	} else if exists {
+		if dest, err = uniqueName(dest, map[string]bool{}); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".ordi-archive-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	format := archiveFormatOf(dest)
+	manifest, err := writeBundle(ctx, tmpPath, format, files, report)
+	if err != nil {
+		return err
+	}
+	if err := verifyBundle(tmpPath, format, manifest); err != nil {
+		return fmt.Errorf("Archiv %s ist fehlerhaft, Originale bleiben erhalten: %w", filepath.Base(dest), err)
+	}
+	if err := os.Rename(tmpPath, dest); err != nil {
+		return err
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+	if err := journal.recordBundle(dest, info); err != nil {
+		return err
+	}
+	stats.Archives = append(stats.Archives, dest)
+
+	for _, entry := range manifest.Files {
+		// A file that changed while it was packed stays; the bundle holds
+		// the older version.
+		info, err := os.Stat(entry.Source)
+		if err != nil || info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+			stats.Busy = append(stats.Busy, inuse.File{Path: entry.Source, Reason: "wurde beim Archivieren verändert"})
+			continue
+		}
+		if err := os.Remove(entry.Source); err != nil {
+			return err
+		}
+		if err := journal.recordArchive(entry.Source, dest, entry.Name, info); err != nil {
+			return err
+		}
+		stats.Archived++
+		stats.ArchivedBytes += entry.Size
+	}
+	return nil
+}
+
+// writeBundle packs files into a new archive at path and returns the
+// manifest that was stored with them.
+func writeBundle(ctx context.Context, path string, format ArchiveFormat, files []FilePreview, report func(FilePreview)) (manifest archiveManifest, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var add func(name string, size int64, modTime time.Time, mode os.FileMode, r io.Reader) error
+	var finish func() error
+	switch format {
+	case ArchiveZip:
+		zw := zip.NewWriter(f)
+		add = func(name string, size int64, modTime time.Time, mode os.FileMode, r io.Reader) error {
+			header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+			header.SetMode(mode)
+			w, err := zw.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, r)
+			return err
+		}
+		finish = zw.Close
+	default:
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			return manifest, err
+		}
+		// Releases the encoder when writing stops early; a second Close
+		// after finish is a no-op.
+		defer zw.Close()
+		tw := tar.NewWriter(zw)
+		add = func(name string, size int64, modTime time.Time, mode os.FileMode, r io.Reader) error {
+			header := &tar.Header{Name: name, Mode: int64(mode.Perm()), Size: size, ModTime: modTime, Typeflag: tar.TypeReg}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			_, err := io.Copy(tw, r)
+			return err
+		}
+		finish = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return zw.Close()
+		}
+	}
+
+	manifest.Created = time.Now()
+	names := make(map[string]bool)
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return manifest, errAborted
+		}
+		if report != nil {
+			report(file)
+		}
+		entry, err := addToBundle(add, file, names)
+		if err != nil {
+			return manifest, fmt.Errorf("%s: %w", file.Rel, err)
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	index, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := add(manifestName, int64(len(index)), manifest.Created, defaultFileMode, strings.NewReader(string(index))); err != nil {
+		return manifest, err
+	}
+	if err := finish(); err != nil {
+		return manifest, err
+	}
+	return manifest, f.Sync()
+}
+
+func addToBundle(add func(string, int64, time.Time, os.FileMode, io.Reader) error, file FilePreview, names map[string]bool) (archivedFile, error) {
+	src, err := os.Open(file.Path)
+	if err != nil {
+		return archivedFile{}, err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return archivedFile{}, err
+	}
+
+	entry := archivedFile{
+		Name:    bundleEntryName(file, names),
+		Source:  file.Path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Mode:    info.Mode().Perm(),
+	}
+	hasher := sha256.New()
+	if err := add(entry.Name, entry.Size, entry.ModTime, entry.Mode, io.TeeReader(src, hasher)); err != nil {
+		return archivedFile{}, err
+	}
+	entry.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	return entry, nil
+}
+
+// bundleEntryName files an archived file under its category, numbering
+// names that are already taken in the bundle.
+func bundleEntryName(file FilePreview, names map[string]bool) string {
+	base, ext := splitName(file.Name)
+	name := file.Category + "/" + file.Name
+	for i := 2; names[name] || name == manifestName; i++ {
+		name = fmt.Sprintf("%s/%s (%d)%s", file.Category, base, i, ext)
+	}
+	names[name] = true
+	return name
+}
+
+// walkBundle calls fn for every entry of the archive at path.
+func walkBundle(path string, format ArchiveFormat, fn func(name string, r io.Reader) error) error {
+	if format == ArchiveZip {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(f.Name, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// verifyBundle reads the archive back and compares every entry with the
+// manifest it was written with.
+func verifyBundle(path string, format ArchiveFormat, manifest archiveManifest) error {
+	expected := make(map[string]archivedFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		expected[file.Name] = file
+	}
+	seenManifest := false
+	err := walkBundle(path, format, func(name string, r io.Reader) error {
+		if name == manifestName {
+			var stored archiveManifest
+			if err := json.NewDecoder(r).Decode(&stored); err != nil {
+				return fmt.Errorf("Manifest unlesbar: %w", err)
+			}
+			if len(stored.Files) != len(manifest.Files) {
+				return fmt.Errorf("Manifest unvollständig")
+			}
+			seenManifest = true
+			return nil
+		}
+		file, ok := expected[name]
+		if !ok {
+			return fmt.Errorf("unerwarteter Eintrag %s", name)
+		}
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, r)
+		if err != nil {
+			return err
+		}
+		if size != file.Size || hex.EncodeToString(hasher.Sum(nil)) != file.SHA256 {
+			return fmt.Errorf("Prüfsumme von %s stimmt nicht", name)
+		}
+		delete(expected, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !seenManifest {
+		return fmt.Errorf("Manifest fehlt")
+	}
+	for name := range expected {
+		return fmt.Errorf("Eintrag %s fehlt", name)
+	}
+	return nil
+}
+
+// extractEntries restores entries of the bundle at path; targets maps entry
+// names to the path each one goes to. Entries are written next to their
+// target first and only moved into place once their checksum matches the
+// manifest; they get back their permissions and modification time. The
+// returned map holds the entries that were restored.
+func extractEntries(path string, targets map[string]string) (map[string]bool, error) {
+	type extracted struct {
+		tmp  string
+		hash string
+	}
+	pending := make(map[string]extracted)
+	defer func() {
+		for _, e := range pending {
+			os.Remove(e.tmp)
+		}
+	}()
+
+	var manifest archiveManifest
+	err := walkBundle(path, archiveFormatOf(path), func(name string, r io.Reader) error {
+		if name == manifestName {
+			return json.NewDecoder(r).Decode(&manifest)
+		}
+		target, ok := targets[name]
+		if !ok {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(target), ".ordi-restore-*.tmp")
+		if err != nil {
+			return err
+		}
+		hasher := sha256.New()
+		_, err = io.Copy(io.MultiWriter(tmp, hasher), r)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		pending[name] = extracted{tmp: tmp.Name(), hash: hex.EncodeToString(hasher.Sum(nil))}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	restored := make(map[string]bool)
+	for _, file := range manifest.Files {
+		e, ok := pending[file.Name]
+		if !ok || e.hash != file.SHA256 {
+			continue
+		}
+		if err := os.Rename(e.tmp, targets[file.Name]); err != nil {
+			continue
+		}
+		delete(pending, file.Name)
+		mode := file.Mode
+		if mode == 0 {
+			mode = defaultFileMode
+		}
+		os.Chmod(targets[file.Name], mode)
+		os.Chtimes(targets[file.Name], file.ModTime, file.ModTime)
+		restored[file.Name] = true
+	}
+	return restored, nil
+}
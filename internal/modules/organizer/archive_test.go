@@ -0,0 +1,116 @@
+package organizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestArchiveRestoresFiles(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTarZst, ArchiveZip} {
+		t.Run(format.String(), func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			old := time.Date(2023, 11, 5, 10, 0, 0, 0, time.Local)
+			files := map[string]os.FileMode{"skript.sh": 0o750, "notiz.txt": 0o600}
+			for name, mode := range files {
+				path := filepath.Join(dir, name)
+				writeFile(t, path, name)
+				if err := os.Chmod(path, mode); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(path, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// An archive file of the user's own is sorted, not bundled.
+			writeFile(t, filepath.Join(dir, "backup.zip"), "zip")
+
+			opts := DefaultOptions()
+			opts.ArchiveAfter = 30
+			opts.ArchiveFormat = format
+			plan, _, _, err := buildPlan([]string{dir}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stats, journal, err := executePlan(context.Background(), dir, plan, nil, opts, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bundle := filepath.Join(dir, archiveDirName, "2023-Q4"+format.ext())
+			if stats.Archived != len(files) || len(stats.Archives) != 1 || stats.Archives[0] != bundle {
+				t.Fatalf("%d Dateien in %v archiviert, erwartet %d in %s", stats.Archived, stats.Archives, len(files), bundle)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "Archive", "backup.zip")); err != nil {
+				t.Errorf("backup.zip nicht einsortiert: %v", err)
+			}
+
+			result, err := Revert(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Restored != len(files)+1 || len(result.Problems) > 0 {
+				t.Fatalf("Revert = %+v", result)
+			}
+			for name, mode := range files {
+				info, err := os.Stat(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !info.ModTime().Equal(old) {
+					t.Errorf("%s: Änderungszeit %v, erwartet %v", name, info.ModTime(), old)
+				}
+				if runtime.GOOS != "windows" && info.Mode().Perm() != mode {
+					t.Errorf("%s: Rechte %v, erwartet %v", name, info.Mode().Perm(), mode)
+				}
+			}
+			if _, err := os.Stat(bundle); !os.IsNotExist(err) {
+				t.Errorf("Archiv %s wurde nicht entfernt", bundle)
+			}
+		})
+	}
+}
+
+func TestArchiveSecondBundle(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	old := time.Date(2023, 11, 5, 10, 0, 0, 0, time.Local)
+	opts := DefaultOptions()
+	opts.ArchiveAfter = 30
+
+	// Two runs for the same quarter: the second never appends to the
+	// bundle of the first.
+	var bundles []string
+	for _, name := range []string{"erste.txt", "zweite.txt"} {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, name)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		plan, _, _, err := buildPlan([]string{dir}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats, _, err := executePlan(context.Background(), dir, plan, nil, opts, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundles = append(bundles, stats.Archives...)
+	}
+
+	want := []string{
+		filepath.Join(dir, archiveDirName, "2023-Q4.tar.zst"),
+		filepath.Join(dir, archiveDirName, "2023-Q4 (2).tar.zst"),
+	}
+	if len(bundles) != len(want) || bundles[0] != want[0] || bundles[1] != want[1] {
+		t.Fatalf("Archive %v, erwartet %v", bundles, want)
+	}
+	for _, bundle := range want {
+		if _, err := os.Stat(bundle); err != nil {
+			t.Error(err)
+		}
+	}
+}
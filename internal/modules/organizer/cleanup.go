@@ -0,0 +1,165 @@
+package organizer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example/ordi/internal/ignore"
+)
+
+// junkFiles are created by file managers and carry no user data; folders
+// that contain nothing else count as empty.
+var junkFiles = map[string]bool{
+	"thumbs.db":   true,
+	"ehthumbs.db": true,
+	"desktop.ini": true,
+	".ds_store":   true,
+	".directory":  true,
+}
+
+func isJunkFile(name string) bool {
+	return junkFiles[strings.ToLower(name)] || strings.HasPrefix(name, "._")
+}
+
+// emptyDir is a folder that can be removed. Junk lists the junk files that
+// are cleared away with it.
+type emptyDir struct {
+	Path string
+	Junk []string
+}
+
+// findEmptyDirs returns the folders below root that are empty or contain only
+// junk files and other such folders. Children come before their parents, so
+// the list can be removed in order. Only the folders a run with opts reads
+// are considered: none without opts.Recursive, and none below opts.MaxDepth.
+// Category folders and ignored folders are never touched.
+func findEmptyDirs(root string, opts Options) ([]emptyDir, error) {
+	if !opts.Recursive {
+		return nil, nil
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := ignore.New(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []emptyDir
+	var visit func(dir string, level int) bool
+	visit = func(dir string, level int) bool {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return false
+		}
+
+		empty := true
+		var junk []string
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			switch {
+			case entry.IsDir():
+				// Folders deeper than the run reached were left as they are.
+				if opts.MaxDepth > 0 && level+1 > opts.MaxDepth {
+					empty = false
+					continue
+				}
+				if (!opts.Flatten && opts.taxonomy().isCategoryDir(root, path)) || matcher.Match(path, true) {
+					empty = false
+					continue
+				}
+				if err := matcher.ReadDir(path); err != nil {
+					empty = false
+					continue
+				}
+				if !visit(path, level+1) {
+					empty = false
+				}
+			case entry.Type().IsRegular() && isJunkFile(entry.Name()):
+				junk = append(junk, path)
+			default:
+				empty = false
+			}
+		}
+
+		if empty && dir != root {
+			found = append(found, emptyDir{Path: dir, Junk: junk})
+		}
+		return empty
+	}
+	visit(root, 0)
+
+	return found, nil
+}
+
+// removeEmptyDirs removes the folders found by findEmptyDirs and records it
+// in journal, the journal of the run that left them empty. Junk files are
+// moved next to the journal instead of being deleted, so reverting the run
+// puts the tree back as it was. Folders that received new content in the
+// meantime stay, since os.Remove only removes empty folders.
+func removeEmptyDirs(journal *Journal, dirs []emptyDir) (int, error) {
+	if err := journal.reopen(); err != nil {
+		return 0, err
+	}
+	removed, failed := 0, 0
+	for _, dir := range dirs {
+		for _, junk := range dir.Junk {
+			if err := stashJunk(journal, junk); err != nil {
+				return removed, err
+			}
+		}
+		if err := os.Remove(dir.Path); err != nil {
+			failed++
+			continue
+		}
+		if err := journal.recordRemovedDir(dir.Path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	if failed > 0 {
+		return removed, fmt.Errorf("%d Ordner konnten nicht entfernt werden", failed)
+	}
+	return removed, nil
+}
+
+// stashJunk moves a junk file into the junk folder of journal and records
+// the move. A file that is gone already is skipped.
+func stashJunk(journal *Journal, junk string) error {
+	if _, err := os.Lstat(junk); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	dir := journal.junkDir()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, fmt.Sprintf("%d-%s", len(journal.Moves), filepath.Base(junk)))
+	if err := moveFile(junk, dest); err != nil {
+		return err
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+	return journal.recordMove(junk, dest, info, false)
+}
+
+// topEmptyDirs returns the entries whose parent is not removed as well, which
+// is what the confirmation screen lists.
+func topEmptyDirs(dirs []emptyDir) []emptyDir {
+	removed := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		removed[dir.Path] = true
+	}
+	var top []emptyDir
+	for _, dir := range dirs {
+		if !removed[filepath.Dir(dir.Path)] {
+			top = append(top, dir)
+		}
+	}
+	return top
+}
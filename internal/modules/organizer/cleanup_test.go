@@ -0,0 +1,196 @@
+package organizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIsJunkFile(t *testing.T) {
+	tests := map[string]bool{
+		"Thumbs.db":    true,
+		"THUMBS.DB":    true,
+		"ehthumbs.db":  true,
+		"desktop.ini":  true,
+		".DS_Store":    true,
+		".directory":   true,
+		"._IMG_1.jpg":  true,
+		"thumbs.db.gz": false,
+		"_notiz.txt":   false,
+		"foto.jpg":     false,
+	}
+	for name, want := range tests {
+		if got := isJunkFile(name); got != want {
+			t.Errorf("isJunkFile(%q) = %v, erwartet %v", name, got, want)
+		}
+	}
+}
+
+// emptyTree creates folders on several levels, some holding only junk.
+func emptyTree(t *testing.T, dir string) {
+	t.Helper()
+	for _, sub := range []string{"leer", "tief/tiefer/am tiefsten", "Bilder", "ignoriert"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(sub)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(dir, "junk", "Thumbs.db"), "vorschau")
+	writeFile(t, filepath.Join(dir, "junk", ".DS_Store"), "finder")
+	writeFile(t, filepath.Join(dir, "voll", "notiz.txt"), "notiz")
+	writeFile(t, filepath.Join(dir, ".ordiignore"), "ignoriert/\n")
+}
+
+func TestFindEmptyDirs(t *testing.T) {
+	tests := []struct {
+		name      string
+		recursive bool
+		maxDepth  int
+		want      []string
+	}{
+		{name: "nicht rekursiv", recursive: false},
+		{name: "Tiefe 1", recursive: true, maxDepth: 1, want: []string{"junk", "leer"}},
+		{name: "Tiefe 2", recursive: true, maxDepth: 2, want: []string{"junk", "leer"}},
+		{
+			name:      "unbegrenzt",
+			recursive: true,
+			want:      []string{"junk", "leer", "tief", "tief/tiefer", "tief/tiefer/am tiefsten"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			emptyTree(t, dir)
+			opts := DefaultOptions()
+			opts.Recursive = tt.recursive
+			opts.MaxDepth = tt.maxDepth
+
+			found, err := findEmptyDirs(dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			junk := 0
+			for _, d := range found {
+				rel, err := filepath.Rel(dir, d.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+				junk += len(d.Junk)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findEmptyDirs = %v, erwartet %v", got, tt.want)
+			}
+			if len(tt.want) > 0 && junk != 2 {
+				t.Errorf("%d Systemdateien gefunden, erwartet 2", junk)
+			}
+		})
+	}
+}
+
+func TestRemoveEmptyDirsRevert(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	emptyTree(t, dir)
+	writeFile(t, filepath.Join(dir, "tief", "bericht.pdf"), "bericht")
+
+	opts := DefaultOptions()
+	opts.Recursive = true
+	opts.MaxDepth = 0
+	plan, _, _, err := buildPlan([]string{dir}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, journal, err := executePlan(context.Background(), dir, plan, nil, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := findEmptyDirs(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := removeEmptyDirs(journal, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 6 {
+		t.Errorf("%d Ordner entfernt, erwartet 6", removed)
+	}
+	for _, sub := range []string{"junk", "leer", "tief", "voll"} {
+		if _, err := os.Stat(filepath.Join(dir, sub)); !os.IsNotExist(err) {
+			t.Errorf("%s wurde nicht entfernt", sub)
+		}
+	}
+
+	read, err := readJournal(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Removed) != 6 {
+		t.Errorf("%d entfernte Ordner im Journal, erwartet 6", len(read.Removed))
+	}
+	result, err := Revert(&read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Partial || len(result.Problems) > 0 {
+		t.Fatalf("Revert = %+v", result)
+	}
+	for _, sub := range []string{"leer", "tief/tiefer/am tiefsten"} {
+		if info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(sub))); err != nil || !info.IsDir() {
+			t.Errorf("Ordner %s nicht wiederhergestellt: %v", sub, err)
+		}
+	}
+	for name, content := range map[string]string{"junk/Thumbs.db": "vorschau", "junk/.DS_Store": "finder", "tief/bericht.pdf": "bericht", "voll/notiz.txt": "notiz"} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("%s: %q, %v; erwartet %q", name, data, err, content)
+		}
+	}
+	if _, err := os.Stat(read.junkDir()); !os.IsNotExist(err) {
+		t.Errorf("Ablage für Systemdateien %s blieb zurück", read.junkDir())
+	}
+}
+
+func TestRemoveEmptyDirsAfterEmptyRun(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "leer"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Recursive = true
+	_, journal, err := executePlan(context.Background(), dir, nil, nil, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The run changed nothing, so its journal was removed on close.
+	if _, err := os.Stat(journal.path); !os.IsNotExist(err) {
+		t.Fatalf("leeres Journal nicht entfernt: %v", err)
+	}
+
+	found, err := findEmptyDirs(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := removeEmptyDirs(journal, found); err != nil {
+		t.Fatal(err)
+	}
+	journals, err := LoadJournals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journals) != 1 || len(journals[0].Removed) != 1 {
+		t.Fatalf("Aufräumen nicht im Journal: %+v", journals)
+	}
+	if _, err := Revert(&journals[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "leer")); err != nil {
+		t.Errorf("Ordner nicht wiederhergestellt: %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package organizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Taxonomy is the set of categories files are sorted into. It is read from
+// categories.json in the ordi config directory; without that file the
+// built-in categories are used.
+//
+// Example:
+//
+//	{
+//	  "categories": [
+//	    {"name": "Bilder", "icon": "📷", "extensions": [".jpg", ".png"],
+//	     "destination": "~/Pictures",
+//	     "subcategories": [{"name": "Screenshots", "patterns": ["^Screenshot"]}]},
+//	    {"name": "Rechnungen", "patterns": ["(?i)rechnung"], "archive_after_days": -1},
+//	    {"name": "Sonstiges", "fallback": true}
+//	  ]
+//	}
+type Taxonomy struct {
+	Categories []Category `json:"categories"`
+
+	fallback Category
+	// flatView is the taxonomy with subcategories merged into their
+	// parents, built on first use.
+	flatOnce sync.Once
+	flatView *Taxonomy
+}
+
+var defaultTaxonomy = mustPrepare(&Taxonomy{Categories: []Category{
+	{Name: "Bilder", Icon: "📷", Extensions: []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff", ".tif", ".webp", ".heic", ".avif", ".ico"},
+		Subcategories: []Category{
+			{Name: "RAW", Extensions: []string{".dng", ".cr2", ".cr3", ".nef", ".arw", ".orf", ".rw2", ".raf"}},
+			{Name: "Vektorgrafiken", Extensions: []string{".svg", ".eps"}},
+			{Name: "Bearbeitung", Extensions: []string{".psd", ".xcf", ".kra"}},
+		}},
+	{Name: "Videos", Icon: "🎬", Extensions: []string{".mp4", ".mkv", ".avi", ".mov", ".wmv", ".webm", ".m4v", ".mpg", ".mpeg", ".flv"},
+		Subcategories: []Category{
+			{Name: "Untertitel", Extensions: []string{".srt", ".vtt", ".ass", ".ssa", ".sub"}},
+		}},
+	{Name: "Musik", Icon: "🎵", Extensions: []string{".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a", ".opus", ".wma", ".aiff"},
+		Subcategories: []Category{
+			{Name: "Hörbücher", Extensions: []string{".m4b"}},
+		}},
+	{Name: "Dokumente", Icon: "📄", Extensions: []string{".pdf"},
+		Subcategories: []Category{
+			{Name: "Texte", Extensions: []string{".doc", ".docx", ".odt", ".rtf", ".txt", ".md", ".pages"}},
+			{Name: "Tabellen", Extensions: []string{".xls", ".xlsx", ".ods", ".csv", ".numbers"}},
+			{Name: "Präsentationen", Extensions: []string{".ppt", ".pptx", ".odp"}},
+			{Name: "E-Books", Icon: "📚", Extensions: []string{".epub", ".mobi", ".azw", ".azw3", ".fb2", ".djvu"}},
+		}},
+	{Name: "Archive", Icon: "📦", Extensions: []string{".zip", ".rar", ".tar", ".tar.gz", ".tgz", ".gz", ".7z", ".tar.xz", ".xz", ".tar.bz2", ".bz2", ".zst"},
+		Subcategories: []Category{
+			{Name: "Code", Extensions: []string{".jar", ".war", ".whl", ".egg", ".gem", ".crate", ".nupkg", ".vsix"}},
+		}},
+	{Name: "Programme", Icon: "💿",
+		Subcategories: []Category{
+			{Name: "Installer", Extensions: []string{".deb", ".rpm", ".appimage", ".flatpak", ".snap", ".exe", ".msi", ".pkg", ".apk"}},
+			{Name: "Abbilder", Extensions: []string{".iso", ".img", ".dmg", ".vhd", ".vhdx", ".qcow2"}},
+		}},
+	{Name: "Schriftarten", Icon: "🔤", Extensions: []string{".ttf", ".otf", ".woff", ".woff2", ".fon"}},
+	{Name: "3D-Modelle", Icon: "🧊", Extensions: []string{".stl", ".3mf", ".obj", ".fbx", ".blend", ".gltf", ".glb", ".step", ".stp", ".dae", ".ply"}},
+	{Name: "Sonstiges", Icon: "📁", Fallback: true},
+}})
+
+// dataDir returns the directory where ordi keeps its configuration and
+// persistent state.
+func dataDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ordi"), nil
+}
+
+func taxonomyPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "categories.json"), nil
+}
+
+// LoadTaxonomy reads the user's category definition. If there is none, the
+// built-in categories are returned. On error the built-in categories are
+// returned together with the error so the caller can still continue.
+func LoadTaxonomy() (*Taxonomy, error) {
+	path, err := taxonomyPath()
+	if err != nil {
+		return defaultTaxonomy, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultTaxonomy, nil
+	}
+	if err != nil {
+		return defaultTaxonomy, err
+	}
+
+	var t Taxonomy
+	if err := json.Unmarshal(data, &t); err != nil {
+		return defaultTaxonomy, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if err := t.prepare(); err != nil {
+		return defaultTaxonomy, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &t, nil
+}
+
+func mustPrepare(t *Taxonomy) *Taxonomy {
+	if err := t.prepare(); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// prepare validates the definition, compiles the filename patterns and
+// resolves the folder of every category.
+func (t *Taxonomy) prepare() error {
+	if len(t.Categories) == 0 {
+		return fmt.Errorf("keine Kategorien definiert")
+	}
+
+	hasFallback := false
+	for i := range t.Categories {
+		if err := t.Categories[i].prepare("", ""); err != nil {
+			return err
+		}
+		if t.Categories[i].Fallback {
+			if hasFallback {
+				return fmt.Errorf("mehr als eine Kategorie ist als \"fallback\" markiert")
+			}
+			hasFallback = true
+			t.fallback = t.Categories[i]
+		}
+	}
+
+	if !hasFallback {
+		other := Category{Name: "Sonstiges", Icon: "📁", Fallback: true}
+		if err := other.prepare("", ""); err != nil {
+			return err
+		}
+		t.Categories = append(t.Categories, other)
+		t.fallback = other
+	}
+	return nil
+}
+
+func (c *Category) prepare(parentPath, parentRoot string) error {
+	if c.Name == "" || c.Name == "." || c.Name == ".." || strings.ContainsAny(c.Name, `/\`) {
+		return fmt.Errorf("ungültiger Kategoriename %q", c.Name)
+	}
+	if c.Icon == "" {
+		c.Icon = "📁"
+	}
+
+	c.Path = c.Name
+	if parentPath != "" {
+		c.Path = parentPath + "/" + c.Name
+	}
+
+	switch {
+	case c.Destination != "":
+		root, err := expandHome(c.Destination)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("Kategorie %q: Ziel muss ein absoluter Pfad sein", c.Path)
+		}
+		c.Root = filepath.Clean(root)
+	case parentRoot != "":
+		c.Root = filepath.Join(parentRoot, c.Name)
+	}
+
+	c.patterns = nil
+	for _, pattern := range c.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("Kategorie %q: ungültiges Muster %q: %w", c.Path, pattern, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+
+	for i := range c.Subcategories {
+		if c.Subcategories[i].Icon == "" {
+			c.Subcategories[i].Icon = c.Icon
+		}
+		if c.Subcategories[i].ArchiveAfterDays == 0 {
+			c.Subcategories[i].ArchiveAfterDays = c.ArchiveAfterDays
+		}
+		if err := c.Subcategories[i].prepare(c.Path, c.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatten returns all categories, parents before their subcategories.
+func (t *Taxonomy) flatten() []Category {
+	var all []Category
+	var walk func(cats []Category)
+	walk = func(cats []Category) {
+		for _, cat := range cats {
+			all = append(all, cat)
+			walk(cat.Subcategories)
+		}
+	}
+	walk(t.Categories)
+	return all
+}
+
+// flat returns the taxonomy with every subcategory merged into its top-level
+// category, for those who prefer a few large folders.
+func (t *Taxonomy) flat() *Taxonomy {
+	t.flatOnce.Do(func() {
+		flat := &Taxonomy{fallback: t.fallback}
+		for _, cat := range t.Categories {
+			flat.Categories = append(flat.Categories, cat.merged())
+		}
+		t.flatView = flat
+	})
+	return t.flatView
+}
+
+// merged returns c with the extensions and patterns of all its
+// subcategories and none of its own. Subcategories come first, as in match.
+func (c Category) merged() Category {
+	var extensions, patterns []string
+	var compiled []*regexp.Regexp
+	for _, sub := range c.Subcategories {
+		sub = sub.merged()
+		extensions = append(extensions, sub.Extensions...)
+		patterns = append(patterns, sub.Patterns...)
+		compiled = append(compiled, sub.patterns...)
+	}
+	c.Extensions = append(extensions, c.Extensions...)
+	c.Patterns = append(patterns, c.Patterns...)
+	c.patterns = append(compiled, c.patterns...)
+	c.Subcategories = nil
+	return c
+}
+
+// isCategoryDir reports whether dir is one of the folders this taxonomy
+// sorts files into, so a recursive scan never picks its own output up again.
+func (t *Taxonomy) isCategoryDir(root, dir string) bool {
+	for _, cat := range t.flatten() {
+		if categoryDir(root, cat) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
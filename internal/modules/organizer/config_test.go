@@ -0,0 +1,153 @@
+package organizer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultTaxonomy(t *testing.T) {
+	paths := make(map[string]bool)
+	owners := make(map[string]string)
+	fallbacks := 0
+	for _, cat := range defaultTaxonomy.flatten() {
+		if paths[cat.Path] {
+			t.Errorf("Kategorie %s doppelt", cat.Path)
+		}
+		paths[cat.Path] = true
+		if cat.Fallback {
+			fallbacks++
+		}
+		if cat.Icon == "" {
+			t.Errorf("%s ohne Symbol", cat.Path)
+		}
+		for _, ext := range cat.Extensions {
+			if ext != strings.ToLower(ext) || !strings.HasPrefix(ext, ".") {
+				t.Errorf("%s: Endung %q", cat.Path, ext)
+			}
+			if owner, ok := owners[ext]; ok {
+				t.Errorf("Endung %s in %s und %s", ext, owner, cat.Path)
+			}
+			owners[ext] = cat.Path
+			if found, _ := defaultTaxonomy.categoryForExt(ext); found.Path != cat.Path {
+				t.Errorf("%s landet in %s statt in %s", ext, found.Path, cat.Path)
+			}
+		}
+	}
+	if fallbacks != 1 || defaultTaxonomy.fallback.Path != "Sonstiges" {
+		t.Errorf("%d Auffangkategorien, Auffang %q", fallbacks, defaultTaxonomy.fallback.Path)
+	}
+	// Subcategories take the symbol of their parent unless they have one.
+	if icon := defaultTaxonomy.getCategoryIcon("Bilder/RAW"); icon != "📷" {
+		t.Errorf("Symbol von Bilder/RAW %q", icon)
+	}
+	if icon := defaultTaxonomy.getCategoryIcon("Dokumente/E-Books"); icon != "📚" {
+		t.Errorf("Symbol von Dokumente/E-Books %q", icon)
+	}
+}
+
+func TestFlatTaxonomy(t *testing.T) {
+	flat := defaultTaxonomy.flat()
+	if flat != defaultTaxonomy.flat() {
+		t.Error("flache Ansicht wird jedes Mal neu gebaut")
+	}
+	if len(flat.Categories) != len(defaultTaxonomy.Categories) {
+		t.Fatalf("%d Kategorien, erwartet %d", len(flat.Categories), len(defaultTaxonomy.Categories))
+	}
+
+	// Every extension of a leaf is kept exactly once, in its top-level
+	// category.
+	want := make(map[string]string)
+	for _, top := range defaultTaxonomy.Categories {
+		var walk func(cat Category)
+		walk = func(cat Category) {
+			for _, ext := range cat.Extensions {
+				want[ext] = top.Path
+			}
+			for _, sub := range cat.Subcategories {
+				walk(sub)
+			}
+		}
+		walk(top)
+	}
+	got := make(map[string]string)
+	for _, cat := range flat.Categories {
+		if len(cat.Subcategories) > 0 {
+			t.Errorf("%s hat in der flachen Ansicht noch Unterkategorien", cat.Path)
+		}
+		for _, ext := range cat.Extensions {
+			if owner, ok := got[ext]; ok {
+				t.Errorf("Endung %s in %s und %s", ext, owner, cat.Path)
+			}
+			got[ext] = cat.Path
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%d Endungen in der flachen Ansicht, erwartet %d", len(got), len(want))
+	}
+	for ext, path := range want {
+		if got[ext] != path {
+			t.Errorf("%s in %q, erwartet %s", ext, got[ext], path)
+		}
+		if found, _ := flat.categoryForExt(ext); found.Path != path {
+			t.Errorf("%s landet in %s, erwartet %s", ext, found.Path, path)
+		}
+	}
+
+	// Files of a subcategory land in its parent.
+	tests := map[string]string{
+		"foto.cr2":      "Bilder",
+		"brief.docx":    "Dokumente",
+		"hörbuch.m4b":   "Musik",
+		"setup.exe":     "Programme",
+		"unbekannt.xyz": "Sonstiges",
+	}
+	for name, path := range tests {
+		if got := flat.getCategory(name).Path; got != path {
+			t.Errorf("%s in %s, erwartet %s", name, got, path)
+		}
+	}
+	if flat.fallback.Path != defaultTaxonomy.fallback.Path {
+		t.Errorf("Auffangkategorie %s", flat.fallback.Path)
+	}
+}
+
+func TestIsCategoryDir(t *testing.T) {
+	pictures := filepath.Join(t.TempDir(), "Pictures")
+	custom := &Taxonomy{Categories: []Category{
+		{Name: "Bilder", Destination: pictures, Subcategories: []Category{{Name: "Screenshots", Patterns: []string{"^Screenshot"}}}},
+		{Name: "Rechnungen", Patterns: []string{"(?i)rechnung"}, Subcategories: []Category{{Name: "2024"}}},
+	}}
+	if err := custom.prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(t.TempDir(), "ziel")
+	tests := []struct {
+		name     string
+		taxonomy *Taxonomy
+		dir      string
+		want     bool
+	}{
+		{"Hauptkategorie", defaultTaxonomy, filepath.Join(root, "Bilder"), true},
+		{"Unterkategorie", defaultTaxonomy, filepath.Join(root, "Dokumente", "Texte"), true},
+		{"Unterkategorie mit Bindestrich", defaultTaxonomy, filepath.Join(root, "Dokumente", "E-Books"), true},
+		{"Auffangkategorie", defaultTaxonomy, filepath.Join(root, "Sonstiges"), true},
+		{"fremder Unterordner", defaultTaxonomy, filepath.Join(root, "Dokumente", "Steuer"), false},
+		{"Unterkategorie ohne Eltern", defaultTaxonomy, filepath.Join(root, "Texte"), false},
+		{"zu tief", defaultTaxonomy, filepath.Join(root, "sub", "Bilder"), false},
+		{"Wurzel", defaultTaxonomy, root, false},
+		{"eigenes Ziel", custom, pictures, true},
+		{"Unterkategorie im eigenen Ziel", custom, filepath.Join(pictures, "Screenshots"), true},
+		{"nicht im Zielordner", custom, filepath.Join(root, "Bilder"), false},
+		{"Unterkategorie ohne Endungen", custom, filepath.Join(root, "Rechnungen", "2024"), true},
+		{"ergänzte Auffangkategorie", custom, filepath.Join(root, "Sonstiges"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.taxonomy.isCategoryDir(root, tt.dir); got != tt.want {
+				t.Errorf("isCategoryDir(%s) = %v, erwartet %v", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
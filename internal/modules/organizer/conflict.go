@@ -0,0 +1,191 @@
+package organizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when a file with the same name already
+// exists in the destination folder.
+type ConflictPolicy int
+
+const (
+	ConflictRename ConflictPolicy = iota
+	ConflictSkip
+	ConflictOverwrite
+	ConflictKeepIfIdentical
+	conflictPolicyCount
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictSkip:
+		return "Überspringen"
+	case ConflictOverwrite:
+		return "Überschreiben"
+	case ConflictKeepIfIdentical:
+		return "Identische verwerfen, sonst umbenennen"
+	default:
+		return "Umbenennen (name (2).ext)"
+	}
+}
+
+// ParseConflictPolicy reads the policy names used on the command line:
+// rename, skip, overwrite and identical.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "rename":
+		return ConflictRename, nil
+	case "skip":
+		return ConflictSkip, nil
+	case "overwrite":
+		return ConflictOverwrite, nil
+	case "identical":
+		return ConflictKeepIfIdentical, nil
+	}
+	return ConflictRename, fmt.Errorf("unbekannte Konfliktregel %q (rename, skip, overwrite, identical)", name)
+}
+
+type conflictAction int
+
+const (
+	actionMove conflictAction = iota
+	actionRename
+	actionSkip
+	actionOverwrite
+	actionDropIdentical
+	actionLinked
+	actionArchive
+)
+
+func (a conflictAction) String() string {
+	switch a {
+	case actionRename:
+		return "wird umbenannt"
+	case actionSkip:
+		return "wird übersprungen"
+	case actionOverwrite:
+		return "überschreibt vorhandene Datei"
+	case actionDropIdentical:
+		return "identisch, Quelle wird entfernt"
+	case actionLinked:
+		return "bereits verknüpft"
+	case actionArchive:
+		return "wird archiviert"
+	default:
+		return ""
+	}
+}
+
+// resolveConflict returns the final destination of srcPath and what has to
+// happen to get it there. claimed holds destinations already taken by earlier
+// files of the same run that may not exist on disk yet. Only files that were
+// there before the run are overwritten; a file of the same run is renamed
+// instead, since its predecessor could not be restored.
+func resolveConflict(srcPath, destPath string, policy ConflictPolicy, claimed map[string]bool) (string, conflictAction, error) {
+	onDisk, err := pathExists(destPath)
+	if err != nil {
+		return "", actionMove, err
+	}
+	if !onDisk && !claimed[destPath] {
+		return destPath, actionMove, nil
+	}
+
+	switch policy {
+	case ConflictSkip:
+		return destPath, actionSkip, nil
+	case ConflictOverwrite:
+		if !claimed[destPath] {
+			return destPath, actionOverwrite, nil
+		}
+	case ConflictKeepIfIdentical:
+		if onDisk {
+			same, err := sameContent(srcPath, destPath)
+			if err != nil {
+				return "", actionMove, err
+			}
+			if same {
+				return destPath, actionDropIdentical, nil
+			}
+		}
+	}
+
+	unique, err := uniqueName(destPath, claimed)
+	if err != nil {
+		return "", actionMove, err
+	}
+	return unique, actionRename, nil
+}
+
+// uniqueName appends " (2)", " (3)", ... to the file name until it is free.
+// The counter goes before compound extensions such as ".tar.gz".
+func uniqueName(destPath string, claimed map[string]bool) (string, error) {
+	dir := filepath.Dir(destPath)
+	base, ext := splitName(filepath.Base(destPath))
+
+	for i := 2; ; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+		exists, err := pathExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists && !claimed[candidate] {
+			return candidate, nil
+		}
+	}
+}
+
+func pathExists(path string) (bool, error) {
+	_, err := os.Lstat(path)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func sameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	hashA, err := fileHash(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := fileHash(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(hashA, hashB), nil
+}
+
+func fileHash(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
@@ -0,0 +1,89 @@
+package organizer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ConflictPolicy
+		onDisk  bool     // a.txt existed before the run
+		content string   // content of the source file
+		claimed []string // destinations taken by earlier files of the run
+		want    string
+		action  conflictAction
+	}{
+		{"frei", ConflictRename, false, "neu", nil, "a.txt", actionMove},
+		{"vorhanden umbenennen", ConflictRename, true, "neu", nil, "a (2).txt", actionRename},
+		{"vorhanden überspringen", ConflictSkip, true, "neu", nil, "a.txt", actionSkip},
+		{"vorhanden überschreiben", ConflictOverwrite, true, "neu", nil, "a.txt", actionOverwrite},
+		{"vorhanden identisch", ConflictKeepIfIdentical, true, "alt", nil, "a.txt", actionDropIdentical},
+		{"vorhanden verschieden", ConflictKeepIfIdentical, true, "neu", nil, "a (2).txt", actionRename},
+		{"im Lauf vergeben überschreiben", ConflictOverwrite, false, "neu", []string{"a.txt"}, "a (2).txt", actionRename},
+		{"im Lauf verschoben überschreiben", ConflictOverwrite, true, "neu", []string{"a.txt"}, "a (2).txt", actionRename},
+		{"im Lauf vergeben überspringen", ConflictSkip, false, "neu", []string{"a.txt"}, "a.txt", actionSkip},
+		{"im Lauf vergeben identisch", ConflictKeepIfIdentical, false, "neu", []string{"a.txt"}, "a (2).txt", actionRename},
+		{"Zähler überspringt vergebene", ConflictRename, true, "neu", []string{"a (2).txt"}, "a (3).txt", actionRename},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "quelle", "a.txt")
+			writeFile(t, src, tt.content)
+			if tt.onDisk {
+				writeFile(t, filepath.Join(dir, "a.txt"), "alt")
+			}
+			claimed := make(map[string]bool)
+			for _, name := range tt.claimed {
+				claimed[filepath.Join(dir, name)] = true
+			}
+
+			dest, action, err := resolveConflict(src, filepath.Join(dir, "a.txt"), tt.policy, claimed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, tt.want); dest != want || action != tt.action {
+				t.Errorf("resolveConflict = %s, %v; erwartet %s, %v", dest, action, want, tt.action)
+			}
+		})
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	tests := []struct {
+		name    string
+		dest    string
+		onDisk  []string
+		claimed []string
+		want    string
+	}{
+		{"erster Zähler", "a.txt", []string{"a.txt"}, nil, "a (2).txt"},
+		{"belegte Zähler", "a.txt", []string{"a.txt", "a (2).txt"}, []string{"a (3).txt"}, "a (4).txt"},
+		{"ohne Endung", "README", []string{"README"}, nil, "README (2)"},
+		{"Doppelendung", "a.tar.gz", []string{"a.tar.gz"}, nil, "a (2).tar.gz"},
+		{"Archiv", "2023-Q4.tar.zst", []string{"2023-Q4.tar.zst"}, nil, "2023-Q4 (2).tar.zst"},
+		{"Punktdatei", ".bashrc", []string{".bashrc"}, nil, ".bashrc (2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.onDisk {
+				writeFile(t, filepath.Join(dir, name), name)
+			}
+			claimed := make(map[string]bool)
+			for _, name := range tt.claimed {
+				claimed[filepath.Join(dir, name)] = true
+			}
+
+			got, err := uniqueName(filepath.Join(dir, tt.dest), claimed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Errorf("uniqueName = %s, erwartet %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package organizer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileType is a file format recognised from the first bytes of a file. Ext is
+// the canonical extension used to look up the category. Weak types are
+// containers shared by several formats (plain ZIP, Ogg, generic MP4); they
+// are only used when the name gives no hint, never to override it.
+type fileType struct {
+	Ext   string
+	Label string
+	Weak  bool
+}
+
+type signature struct {
+	offset int
+	magic  []byte
+	typ    fileType
+}
+
+var signatures = []signature{
+	{0, []byte{0xFF, 0xD8, 0xFF}, fileType{Ext: ".jpg", Label: "JPEG-Bild"}},
+	{0, []byte("\x89PNG\r\n\x1a\n"), fileType{Ext: ".png", Label: "PNG-Bild"}},
+	{0, []byte("GIF87a"), fileType{Ext: ".gif", Label: "GIF-Bild"}},
+	{0, []byte("GIF89a"), fileType{Ext: ".gif", Label: "GIF-Bild"}},
+	{0, []byte("II*\x00"), fileType{Ext: ".tiff", Label: "TIFF-Bild"}},
+	{0, []byte("MM\x00*"), fileType{Ext: ".tiff", Label: "TIFF-Bild"}},
+	{0, []byte("BM"), fileType{Ext: ".bmp", Label: "BMP-Bild", Weak: true}},
+	{0, []byte("%PDF-"), fileType{Ext: ".pdf", Label: "PDF-Dokument"}},
+	{0, []byte{0x1A, 0x45, 0xDF, 0xA3}, fileType{Ext: ".mkv", Label: "Matroska-Video"}},
+	{0, []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11}, fileType{Ext: ".wmv", Label: "Windows-Media-Video"}},
+	{0, []byte("ID3"), fileType{Ext: ".mp3", Label: "MP3-Audio"}},
+	{0, []byte("fLaC"), fileType{Ext: ".flac", Label: "FLAC-Audio"}},
+	{0, []byte("OggS"), fileType{Ext: ".ogg", Label: "Ogg-Container", Weak: true}},
+	{0, []byte("Rar!\x1a\x07"), fileType{Ext: ".rar", Label: "RAR-Archiv"}},
+	{0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, fileType{Ext: ".7z", Label: "7z-Archiv"}},
+	{0, []byte{0x1F, 0x8B}, fileType{Ext: ".gz", Label: "GZIP-Archiv"}},
+	{257, []byte("ustar"), fileType{Ext: ".tar", Label: "TAR-Archiv"}},
+}
+
+// detectType reads the start of the file and returns its format, if known.
+func detectType(path string) (fileType, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileType{}, false
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+
+	if len(header) >= 12 && bytes.Equal(header[:4], []byte("RIFF")) {
+		switch string(header[8:12]) {
+		case "WAVE":
+			return fileType{Ext: ".wav", Label: "WAV-Audio"}, true
+		case "AVI ":
+			return fileType{Ext: ".avi", Label: "AVI-Video"}, true
+		case "WEBP":
+			return fileType{Ext: ".webp", Label: "WebP-Bild"}, true
+		}
+	}
+
+	if len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")) {
+		return isoMediaType(string(header[8:12])), true
+	}
+
+	if bytes.HasPrefix(header, []byte("PK\x03\x04")) {
+		return zipType(f), true
+	}
+
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(header) >= end && bytes.Equal(header[sig.offset:end], sig.magic) {
+			return sig.typ, true
+		}
+	}
+
+	// MPEG audio frames without an ID3 tag start with an 11 bit sync word.
+	if len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 != 0 {
+		return fileType{Ext: ".mp3", Label: "MPEG-Audio", Weak: true}, true
+	}
+
+	return fileType{}, false
+}
+
+// isoMediaType maps the major brand of an ISO base media file (MP4, MOV,
+// M4A, HEIC) to a format.
+func isoMediaType(brand string) fileType {
+	switch brand {
+	case "M4A ", "M4B ", "M4P ":
+		return fileType{Ext: ".m4a", Label: "MPEG-4-Audio"}
+	case "qt  ":
+		return fileType{Ext: ".mov", Label: "QuickTime-Video"}
+	case "heic", "heix", "mif1", "msf1":
+		return fileType{Ext: ".heic", Label: "HEIC-Bild"}
+	case "avif":
+		return fileType{Ext: ".avif", Label: "AVIF-Bild"}
+	case "M4V ", "M4VH", "M4VP":
+		return fileType{Ext: ".mp4", Label: "MPEG-4-Video"}
+	default:
+		// isom, mp41, mp42, ... are used for audio-only files as well.
+		return fileType{Ext: ".mp4", Label: "MPEG-4-Container", Weak: true}
+	}
+}
+
+// zipType looks inside a ZIP file to tell office documents, ebooks and plain
+// archives apart.
+func zipType(f *os.File) fileType {
+	plain := fileType{Ext: ".zip", Label: "ZIP-Archiv", Weak: true}
+
+	info, err := f.Stat()
+	if err != nil {
+		return plain
+	}
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return plain
+	}
+
+	for _, file := range r.File {
+		if file.Name != "mimetype" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			break
+		}
+		mime, _ := io.ReadAll(io.LimitReader(rc, 100))
+		rc.Close()
+		switch strings.TrimSpace(string(mime)) {
+		case "application/epub+zip":
+			return fileType{Ext: ".epub", Label: "EPUB-E-Book"}
+		case "application/vnd.oasis.opendocument.text":
+			return fileType{Ext: ".odt", Label: "OpenDocument-Text"}
+		case "application/vnd.oasis.opendocument.spreadsheet":
+			return fileType{Ext: ".ods", Label: "OpenDocument-Tabelle"}
+		case "application/vnd.oasis.opendocument.presentation":
+			return fileType{Ext: ".odp", Label: "OpenDocument-Präsentation"}
+		}
+	}
+
+	for _, file := range r.File {
+		switch {
+		case strings.HasPrefix(file.Name, "word/"):
+			return fileType{Ext: ".docx", Label: "Word-Dokument"}
+		case strings.HasPrefix(file.Name, "xl/"):
+			return fileType{Ext: ".xlsx", Label: "Excel-Tabelle"}
+		case strings.HasPrefix(file.Name, "ppt/"):
+			return fileType{Ext: ".pptx", Label: "PowerPoint-Präsentation"}
+		}
+	}
+	return plain
+}
+
+// classify returns the category of the file at path. The name decides unless
+// it has no known extension or the content clearly contradicts it; mismatch
+// reports the latter case. A category chosen by a filename pattern always
+// stands, a contradicting extension is only flagged.
+func (t *Taxonomy) classify(path string) (cat Category, detected fileType, mismatch bool) {
+	name := filepath.Base(path)
+	byName := t.getCategory(name)
+
+	detected, ok := detectType(path)
+	if !ok {
+		return byName, fileType{}, false
+	}
+	byContent, known := t.categoryForExt(detected.Ext)
+	if !known {
+		return byName, detected, false
+	}
+
+	if byName.Path == t.fallback.Path {
+		return byContent, detected, hasExtension(name)
+	}
+	if detected.Weak {
+		return byName, detected, false
+	}
+	byExt := t.extensionCategory(name)
+	if topLevel(byExt.Path) == topLevel(byContent.Path) {
+		return byName, detected, false
+	}
+	if byExt.Path != byName.Path {
+		return byName, detected, byExt.Path != t.fallback.Path
+	}
+	return byContent, detected, true
+}
+
+func hasExtension(name string) bool {
+	ext := filepath.Ext(name)
+	return ext != "" && ext != name
+}
+
+func topLevel(categoryPath string) string {
+	if i := strings.Index(categoryPath, "/"); i >= 0 {
+		return categoryPath[:i]
+	}
+	return categoryPath
+}
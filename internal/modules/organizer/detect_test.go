@@ -0,0 +1,45 @@
+package organizer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	taxonomy := mustPrepare(&Taxonomy{Categories: []Category{
+		{Name: "Rechnungen", Patterns: []string{"(?i)rechnung"}},
+		{Name: "Dokumente", Extensions: []string{".pdf"}},
+		{Name: "Bilder", Extensions: []string{".jpg", ".png"}},
+		{Name: "Sonstiges", Fallback: true},
+	}})
+	const (
+		pdf = "%PDF-1.4\n"
+		png = "\x89PNG\r\n\x1a\n"
+	)
+	tests := []struct {
+		name     string
+		content  string
+		want     string
+		mismatch bool
+	}{
+		{"Rechnung.pdf", pdf, "Rechnungen", false},
+		{"Rechnung.jpg", pdf, "Rechnungen", true},
+		{"Rechnung", pdf, "Rechnungen", false},
+		{"foto.jpg", png, "Bilder", false},
+		{"foto.jpg", pdf, "Dokumente", true},
+		{"scan", pdf, "Dokumente", false},
+		{"scan.xyz", png, "Bilder", true},
+		{"notiz.pdf", "nur Text", "Dokumente", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			writeFile(t, path, tt.content)
+
+			cat, _, mismatch := taxonomy.classify(path)
+			if cat.Path != tt.want || mismatch != tt.mismatch {
+				t.Errorf("classify(%s) = %s, %v; erwartet %s, %v", tt.name, cat.Path, mismatch, tt.want, tt.mismatch)
+			}
+		})
+	}
+}
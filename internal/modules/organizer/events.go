@@ -0,0 +1,155 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// eventGapPresets are the selectable pauses in hours after which the next
+// photo starts a new event.
+var eventGapPresets = []int{1, 2, 4, 6, 12, 24, 48}
+
+// isPhoto reports whether cat is the category images go to (or one of its
+// subcategories).
+func (t *Taxonomy) isPhoto(cat Category) bool {
+	images, ok := t.categoryForExt(".jpg")
+	return ok && topLevel(cat.Path) == topLevel(images.Path)
+}
+
+// photoEvent is a run of photos without a pause longer than the gap.
+type photoEvent struct {
+	// Key is the default folder name, e.g. "2024-07-13 – 2024-07-15", or
+	// "2024-07-13 09.30" if the day has several events. Names given in the
+	// preview are stored under it.
+	Key   string
+	Start time.Time
+	End   time.Time
+	// Files are the indices of the photos in the plan.
+	Files []int
+}
+
+// shotTime is when a photo was taken, or its modification time if it has no
+// EXIF date.
+func (v templateVars) shotTime() time.Time {
+	if v.taken.IsZero() {
+		return v.modTime
+	}
+	return v.taken
+}
+
+// photoEvents groups the photos of a plan into events in chronological
+// order. Excluded files and files that are archived instead do not count.
+func photoEvents(plan []FilePreview, opts Options) []photoEvent {
+	if opts.EventGap <= 0 {
+		return nil
+	}
+
+	var photos []int
+	for i, file := range plan {
+		if file.excluded() || file.CompanionOf != "" || !opts.taxonomy().isPhoto(file.vars.category) {
+			continue
+		}
+		if _, archived := opts.archiveBundle("", file.vars); archived {
+			continue
+		}
+		photos = append(photos, i)
+	}
+	sort.SliceStable(photos, func(a, b int) bool {
+		return plan[photos[a]].vars.shotTime().Before(plan[photos[b]].vars.shotTime())
+	})
+
+	gap := time.Duration(opts.EventGap) * time.Hour
+	var events []photoEvent
+	for _, i := range photos {
+		when := plan[i].vars.shotTime()
+		if n := len(events); n > 0 && when.Sub(events[n-1].End) <= gap {
+			events[n-1].End = when
+			events[n-1].Files = append(events[n-1].Files, i)
+			continue
+		}
+		events = append(events, photoEvent{Start: when, End: when, Files: []int{i}})
+	}
+
+	// With a gap below a day several events can share a day; they are told
+	// apart by their start time.
+	days := make(map[string]int)
+	for i := range events {
+		events[i].Key = eventKey(events[i].Start, events[i].End, false)
+		days[events[i].Key]++
+	}
+	for i := range events {
+		if days[events[i].Key] > 1 {
+			events[i].Key = eventKey(events[i].Start, events[i].End, true)
+		}
+	}
+	return events
+}
+
+// assignEvents stores the event of every photo in its template variables, so
+// destination can put it into the event folder.
+func assignEvents(plan []FilePreview, opts Options) {
+	for i := range plan {
+		plan[i].vars.event = ""
+	}
+	for _, event := range photoEvents(plan, opts) {
+		for _, i := range event.Files {
+			plan[i].vars.event = event.Key
+		}
+	}
+}
+
+// eventKey names an event by the days it spans, and with withTime by the
+// time it starts as well.
+func eventKey(start, end time.Time, withTime bool) string {
+	first, last := start.Format("2006-01-02"), end.Format("2006-01-02")
+	key := first
+	if withTime {
+		key = start.Format("2006-01-02 15.04")
+	}
+	if first == last {
+		return key
+	}
+	return key + " – " + last
+}
+
+// eventName returns the folder name of an event: the name given in the
+// preview, or else its default name.
+func (o Options) eventName(key string) string {
+	if name := strings.TrimSpace(o.EventNames[key]); name != "" {
+		return musicSegment(name, key)
+	}
+	return key
+}
+
+// eventPath builds <category>/<event>/<name><ext>.
+func (o Options) eventPath(root string, v templateVars) string {
+	return filepath.Join(categoryDir(root, v.category), o.eventName(v.event), sanitizeSegment(v.name+v.ext))
+}
+
+// eventGapLabel describes the gap setting.
+func eventGapLabel(hours int) string {
+	if hours == 0 {
+		return "Nein"
+	}
+	return fmt.Sprintf("neues Ereignis nach %d Std. Pause", hours)
+}
+
+// stepEventGap moves to the next shorter or longer preset, stopping at
+// either end, so the gap can be tuned in the preview without turning events
+// off.
+func stepEventGap(hours, delta int) int {
+	current := 0
+	for i, preset := range eventGapPresets {
+		if preset == hours {
+			current = i
+		}
+	}
+	next := current + delta
+	if next < 0 || next >= len(eventGapPresets) {
+		return hours
+	}
+	return eventGapPresets[next]
+}
@@ -0,0 +1,46 @@
+package organizer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPhotoEvents(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 7, day, hour, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name  string
+		gap   int
+		shots []time.Time
+		want  []string
+	}{
+		{"ein Tag", 6, []time.Time{at(13, 8), at(13, 12)}, []string{"2024-07-13"}},
+		{"mehrere Tage", 24, []time.Time{at(13, 8), at(14, 7), at(15, 6)}, []string{"2024-07-13 – 2024-07-15"}},
+		{"zwei Ereignisse an einem Tag", 4, []time.Time{at(13, 8), at(13, 9), at(13, 15)}, []string{"2024-07-13 08.00", "2024-07-13 15.00"}},
+		{"über Mitternacht und am selben Tag", 2, []time.Time{at(12, 23), at(13, 0), at(13, 9)}, []string{"2024-07-12 – 2024-07-13", "2024-07-13"}},
+		{"unsortiert", 12, []time.Time{at(20, 10), at(13, 8)}, []string{"2024-07-13", "2024-07-20"}},
+	}
+	photo := defaultTaxonomy.getCategory("foto.jpg")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.EventGap = tt.gap
+			var plan []FilePreview
+			for _, shot := range tt.shots {
+				plan = append(plan, FilePreview{vars: templateVars{category: photo, taken: shot}})
+			}
+			// Documents never belong to an event.
+			plan = append(plan, FilePreview{vars: templateVars{category: defaultTaxonomy.getCategory("brief.pdf"), modTime: at(13, 9)}})
+
+			var keys []string
+			for _, event := range photoEvents(plan, opts) {
+				keys = append(keys, event.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("Ereignisse %q, erwartet %q", keys, tt.want)
+			}
+		})
+	}
+}
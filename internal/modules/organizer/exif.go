@@ -0,0 +1,271 @@
+package organizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"example/ordi/internal/places"
+)
+
+// exifData holds the few EXIF fields the organizer uses.
+type exifData struct {
+	Taken time.Time
+	// GPS is nil for photos without a recorded position.
+	GPS *places.Point
+}
+
+const (
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+
+	typeRational = 5
+)
+
+var errNoExif = errors.New("keine EXIF-Daten")
+
+// readExif extracts EXIF data from JPEG files and TIFF based files (which
+// includes most RAW formats).
+func readExif(path string) (exifData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return exifData{}, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic, err := r.Peek(4)
+	if err != nil {
+		return exifData{}, errNoExif
+	}
+
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		tiff, err := jpegExifSegment(r)
+		if err != nil {
+			return exifData{}, err
+		}
+		return parseTIFF(tiff)
+	case bytes.Equal(magic, []byte("II*\x00")) || bytes.Equal(magic, []byte("MM\x00*")):
+		// The IFDs of RAW files can be anywhere, but the first megabytes
+		// always contain the date tags.
+		tiff, err := io.ReadAll(io.LimitReader(r, 4<<20))
+		if err != nil {
+			return exifData{}, err
+		}
+		return parseTIFF(tiff)
+	}
+	return exifData{}, errNoExif
+}
+
+// jpegExifSegment walks the JPEG markers up to the APP1 Exif segment and
+// returns its TIFF payload.
+func jpegExifSegment(r *bufio.Reader) ([]byte, error) {
+	if _, err := r.Discard(2); err != nil {
+		return nil, err
+	}
+	for {
+		var marker [2]byte
+		if _, err := io.ReadFull(r, marker[:]); err != nil {
+			return nil, errNoExif
+		}
+		if marker[0] != 0xFF {
+			return nil, errNoExif
+		}
+		// Start of scan or end of image: no metadata follows.
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, errNoExif
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return nil, errNoExif
+		}
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, errNoExif
+		}
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+	}
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	tag    uint16
+	typ    uint16
+	count  uint32
+	offset uint32
+	raw    []byte
+}
+
+func parseTIFF(data []byte) (exifData, error) {
+	if len(data) < 8 {
+		return exifData{}, errNoExif
+	}
+	t := tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return exifData{}, errNoExif
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return exifData{}, err
+	}
+
+	var result exifData
+	var dateTime string
+	for _, e := range ifd0 {
+		switch e.tag {
+		case tagDateTime:
+			dateTime = t.ascii(e)
+		case tagExifIFD:
+			exifIFD, err := t.readIFD(e.offset)
+			if err != nil {
+				continue
+			}
+			for _, sub := range exifIFD {
+				if sub.tag == tagDateTimeOriginal {
+					result.Taken = parseExifTime(t.ascii(sub))
+				}
+			}
+		case tagGPSIFD:
+			gpsIFD, err := t.readIFD(e.offset)
+			if err != nil {
+				continue
+			}
+			result.GPS = t.gps(gpsIFD)
+		}
+	}
+	if result.Taken.IsZero() {
+		result.Taken = parseExifTime(dateTime)
+	}
+	return result, nil
+}
+
+func (t tiffReader) readIFD(offset uint32) ([]ifdEntry, error) {
+	if int(offset)+2 > len(t.data) {
+		return nil, errNoExif
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(t.data) {
+		return nil, errNoExif
+	}
+
+	entries := make([]ifdEntry, 0, count)
+	for i := 0; i < count; i++ {
+		b := t.data[start+i*12 : start+(i+1)*12]
+		entries = append(entries, ifdEntry{
+			tag:    t.order.Uint16(b[0:2]),
+			typ:    t.order.Uint16(b[2:4]),
+			count:  t.order.Uint32(b[4:8]),
+			offset: t.order.Uint32(b[8:12]),
+			raw:    b[8:12],
+		})
+	}
+	return entries, nil
+}
+
+// ascii returns the value of an ASCII entry; values of up to four bytes are
+// stored inline instead of at an offset.
+func (t tiffReader) ascii(e ifdEntry) string {
+	n := int(e.count)
+	var b []byte
+	if n <= 4 {
+		b = e.raw[:n]
+	} else {
+		if int(e.offset)+n > len(t.data) {
+			return ""
+		}
+		b = t.data[e.offset : int(e.offset)+n]
+	}
+	return strings.TrimRight(string(b), "\x00 ")
+}
+
+// gps reads the position from the GPS IFD. It returns nil if latitude or
+// longitude are missing or out of range.
+func (t tiffReader) gps(entries []ifdEntry) *places.Point {
+	var lat, lon []float64
+	latRef, lonRef := "N", "E"
+	for _, e := range entries {
+		switch e.tag {
+		case tagGPSLatitudeRef:
+			latRef = t.ascii(e)
+		case tagGPSLatitude:
+			lat = t.rationals(e)
+		case tagGPSLongitudeRef:
+			lonRef = t.ascii(e)
+		case tagGPSLongitude:
+			lon = t.rationals(e)
+		}
+	}
+	if len(lat) != 3 || len(lon) != 3 {
+		return nil
+	}
+
+	p := places.Point{
+		Lat: lat[0] + lat[1]/60 + lat[2]/3600,
+		Lon: lon[0] + lon[1]/60 + lon[2]/3600,
+	}
+	if latRef == "S" {
+		p.Lat = -p.Lat
+	}
+	if lonRef == "W" {
+		p.Lon = -p.Lon
+	}
+	// Cameras without a fix often write 0/0.
+	if p.Lat == 0 && p.Lon == 0 || p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return nil
+	}
+	return &p
+}
+
+// rationals returns the values of an unsigned RATIONAL entry, which are
+// always stored at an offset.
+func (t tiffReader) rationals(e ifdEntry) []float64 {
+	n := int(e.count)
+	if e.typ != typeRational || n > 16 || int(e.offset)+n*8 > len(t.data) {
+		return nil
+	}
+	values := make([]float64, n)
+	for i := range values {
+		b := t.data[int(e.offset)+i*8:]
+		num, den := t.order.Uint32(b[0:4]), t.order.Uint32(b[4:8])
+		if den == 0 {
+			return nil
+		}
+		values[i] = float64(num) / float64(den)
+	}
+	return values
+}
+
+func parseExifTime(s string) time.Time {
+	taken, err := time.ParseInLocation("2006:01:02 15:04:05", s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return taken
+}
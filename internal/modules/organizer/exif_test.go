@@ -0,0 +1,219 @@
+package organizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// tiffEntry is one IFD entry for buildTIFF; values of up to four bytes are
+// stored inline, longer ones behind the IFD.
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// tiffOrder is a byte order that can also append.
+type tiffOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+type tiffBuilder struct {
+	order tiffOrder
+	data  []byte
+}
+
+func newTIFF(order tiffOrder) *tiffBuilder {
+	b := &tiffBuilder{order: order}
+	if order == binary.LittleEndian {
+		b.data = append(b.data, 'I', 'I')
+	} else {
+		b.data = append(b.data, 'M', 'M')
+	}
+	b.data = order.AppendUint16(b.data, 42)
+	b.data = order.AppendUint32(b.data, 0)
+	return b
+}
+
+// ifd appends an IFD with its values and returns its offset.
+func (b *tiffBuilder) ifd(entries []tiffEntry) uint32 {
+	offset := uint32(len(b.data))
+	dataAt := offset + 2 + uint32(len(entries))*12 + 4
+	var values []byte
+	b.data = b.order.AppendUint16(b.data, uint16(len(entries)))
+	for _, e := range entries {
+		b.data = b.order.AppendUint16(b.data, e.tag)
+		b.data = b.order.AppendUint16(b.data, e.typ)
+		b.data = b.order.AppendUint32(b.data, e.count)
+		if len(e.value) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, e.value)
+			b.data = append(b.data, inline...)
+			continue
+		}
+		b.data = b.order.AppendUint32(b.data, dataAt+uint32(len(values)))
+		values = append(values, e.value...)
+	}
+	b.data = b.order.AppendUint32(b.data, 0)
+	b.data = append(b.data, values...)
+	return offset
+}
+
+// finish points the header at ifd0 and returns the TIFF data.
+func (b *tiffBuilder) finish(ifd0 uint32) []byte {
+	b.order.PutUint32(b.data[4:8], ifd0)
+	return b.data
+}
+
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func longEntry(order tiffOrder, tag uint16, v uint32) tiffEntry {
+	return tiffEntry{tag: tag, typ: 4, count: 1, value: order.AppendUint32(nil, v)}
+}
+
+// degreesEntry stores an angle as degrees, minutes and seconds.
+func degreesEntry(order tiffOrder, tag uint16, angle float64) tiffEntry {
+	deg := math.Floor(angle)
+	minutes := math.Floor((angle - deg) * 60)
+	sec := ((angle-deg)*60 - minutes) * 60
+	var value []byte
+	for _, r := range [][2]uint32{{uint32(deg), 1}, {uint32(minutes), 1}, {uint32(math.Round(sec * 1000)), 1000}} {
+		value = order.AppendUint32(value, r[0])
+		value = order.AppendUint32(value, r[1])
+	}
+	return tiffEntry{tag: tag, typ: typeRational, count: 3, value: value}
+}
+
+type exifFixture struct {
+	dateTime string
+	original string
+	gps      bool
+	lat, lon float64
+}
+
+func buildTIFF(order tiffOrder, f exifFixture) []byte {
+	b := newTIFF(order)
+	var ifd0 []tiffEntry
+	if f.dateTime != "" {
+		ifd0 = append(ifd0, asciiEntry(tagDateTime, f.dateTime))
+	}
+	if f.original != "" {
+		sub := b.ifd([]tiffEntry{asciiEntry(tagDateTimeOriginal, f.original)})
+		ifd0 = append(ifd0, longEntry(order, tagExifIFD, sub))
+	}
+	if f.gps {
+		latRef, lonRef := "N", "E"
+		lat, lon := f.lat, f.lon
+		if lat < 0 {
+			latRef, lat = "S", -lat
+		}
+		if lon < 0 {
+			lonRef, lon = "W", -lon
+		}
+		sub := b.ifd([]tiffEntry{
+			asciiEntry(tagGPSLatitudeRef, latRef),
+			degreesEntry(order, tagGPSLatitude, lat),
+			asciiEntry(tagGPSLongitudeRef, lonRef),
+			degreesEntry(order, tagGPSLongitude, lon),
+		})
+		ifd0 = append(ifd0, longEntry(order, tagGPSIFD, sub))
+	}
+	return b.finish(b.ifd(ifd0))
+}
+
+func TestParseTIFF(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture exifFixture
+		taken   time.Time
+		gps     bool
+		lat     float64
+		lon     float64
+	}{
+		{
+			name:    "nur DateTime",
+			fixture: exifFixture{dateTime: "2021:06:01 08:15:00"},
+			taken:   time.Date(2021, 6, 1, 8, 15, 0, 0, time.Local),
+		},
+		{
+			name:    "DateTimeOriginal vor DateTime",
+			fixture: exifFixture{dateTime: "2024:01:02 10:00:00", original: "2023:12:24 18:30:05"},
+			taken:   time.Date(2023, 12, 24, 18, 30, 5, 0, time.Local),
+		},
+		{
+			name:    "ungültiges Datum",
+			fixture: exifFixture{dateTime: "0000:00:00 00:00:00"},
+		},
+		{
+			name:    "GPS Nord-Ost",
+			fixture: exifFixture{original: "2022:08:15 12:00:00", gps: true, lat: 41.9028, lon: 12.4964},
+			taken:   time.Date(2022, 8, 15, 12, 0, 0, 0, time.Local),
+			gps:     true,
+			lat:     41.9028,
+			lon:     12.4964,
+		},
+		{
+			name:    "GPS Süd-West",
+			fixture: exifFixture{gps: true, lat: -22.9068, lon: -43.1729},
+			gps:     true,
+			lat:     -22.9068,
+			lon:     -43.1729,
+		},
+		{
+			name:    "GPS ohne Fix",
+			fixture: exifFixture{gps: true},
+		},
+	}
+	for _, order := range []tiffOrder{binary.LittleEndian, binary.BigEndian} {
+		for _, tt := range tests {
+			t.Run(order.String()+"/"+tt.name, func(t *testing.T) {
+				got, err := parseTIFF(buildTIFF(order, tt.fixture))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.Taken.Equal(tt.taken) {
+					t.Errorf("Aufnahmezeit %v, erwartet %v", got.Taken, tt.taken)
+				}
+				if !tt.gps {
+					if got.GPS != nil {
+						t.Errorf("Position %+v, erwartet keine", *got.GPS)
+					}
+					return
+				}
+				if got.GPS == nil {
+					t.Fatal("keine Position gelesen")
+				}
+				if math.Abs(got.GPS.Lat-tt.lat) > 1e-5 || math.Abs(got.GPS.Lon-tt.lon) > 1e-5 {
+					t.Errorf("Position %v, %v, erwartet %v, %v", got.GPS.Lat, got.GPS.Lon, tt.lat, tt.lon)
+				}
+			})
+		}
+	}
+}
+
+func TestParseTIFFInvalid(t *testing.T) {
+	valid := buildTIFF(binary.LittleEndian, exifFixture{dateTime: "2021:06:01 08:15:00"})
+	outOfRange := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint32(outOfRange[4:8], uint32(len(valid)+100))
+	tests := map[string][]byte{
+		"leer":                nil,
+		"zu kurz":             []byte("II*\x00"),
+		"falsche Bytefolge":   []byte("XX*\x00\x08\x00\x00\x00\x00\x00"),
+		"IFD hinter dem Ende": outOfRange,
+		"abgeschnitten":       valid[:12],
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseTIFF(data); !errors.Is(err, errNoExif) {
+				t.Errorf("parseTIFF = %v, erwartet %v", err, errNoExif)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"example/ordi/internal/inuse"
+)
+
+// FlattenNaming decides how files that would get the same name in the
+// flattened folder are told apart. Files without a collision keep their
+// name either way.
+type FlattenNaming int
+
+const (
+	FlattenPrefix FlattenNaming = iota
+	FlattenCounter
+	flattenNamingCount
+)
+
+func (n FlattenNaming) String() string {
+	if n == FlattenCounter {
+		return "Zähler anhängen (IMG_0001 (2).jpg)"
+	}
+	return "Ordnerpfad voranstellen (100CANON_IMG_0001.jpg)"
+}
+
+// flattenOptionItems are the options offered when flattening a folder.
+var flattenOptionItems = []optionItem{
+	targetOption,
+	{
+		label: "Bei Namenskonflikt",
+		value: func(o Options) string { return o.FlattenNaming.String() },
+		step: func(o *Options, delta int) {
+			o.FlattenNaming = FlattenNaming(wrap(int(o.FlattenNaming)+delta, int(flattenNamingCount)))
+		},
+	},
+}
+
+// buildFlattenPlan plans moving every file below the source folders into
+// the target folder itself. Files already directly in the target stay.
+func buildFlattenPlan(sources []string, opts Options) (plan []FilePreview, ignored int, busy []inuse.File, err error) {
+	var files []sourceFile
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		root, err := filepath.Abs(source)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		target, err := opts.targetRoot(root)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		found, n, err := collectFiles(root, opts)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		for _, file := range found {
+			if filepath.Dir(file.Path) != target {
+				files = append(files, file)
+			}
+		}
+		ignored += n
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+	}
+	if !opts.readOnly {
+		busy = inuse.Check(paths)
+	}
+	skip := inuse.Set(busy)
+
+	for _, file := range files {
+		if skip[file.Path] {
+			continue
+		}
+		folder := filepath.ToSlash(filepath.Dir(file.Rel))
+		if folder == "." {
+			folder = filepath.Base(file.Root)
+		}
+		base, ext := splitName(file.Info.Name())
+		plan = append(plan, FilePreview{
+			Name:     file.Info.Name(),
+			Source:   file.Root,
+			Path:     file.Path,
+			Rel:      file.Rel,
+			Depth:    file.Depth,
+			Category: folder,
+			Icon:     "📁",
+			Size:     file.Info.Size(),
+			vars: templateVars{
+				name:    base,
+				ext:     ext,
+				modTime: file.Info.ModTime(),
+				size:    file.Info.Size(),
+			},
+		})
+	}
+
+	linkCompanions(plan)
+	if err := flattenTargets(plan, opts); err != nil {
+		return nil, 0, nil, err
+	}
+	return plan, ignored, busy, nil
+}
+
+// flattenTargets gives every file of a flatten plan its name in the target
+// folder. The first file keeps its name; later ones with the same name get
+// their folder path as prefix or a counter.
+func flattenTargets(plan []FilePreview, opts Options) error {
+	claimed := make(map[string]bool)
+	for i := range plan {
+		entry := &plan[i]
+		if entry.excluded() || entry.CompanionOf != "" {
+			continue
+		}
+		target, err := opts.targetRoot(entry.Source)
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(target, entry.Name)
+		if entry.manual != "" {
+			dest = entry.manual
+		}
+		entry.target = dest
+		entry.Action = actionMove
+		taken, err := pathExists(dest)
+		if err != nil {
+			return err
+		}
+		if taken || claimed[dest] {
+			entry.Action = actionRename
+			if opts.FlattenNaming == FlattenPrefix && entry.manual == "" {
+				dest = filepath.Join(target, flatName(entry.Rel))
+				taken, err = pathExists(dest)
+				if err != nil {
+					return err
+				}
+			}
+			if taken || claimed[dest] {
+				if dest, err = uniqueName(dest, claimed); err != nil {
+					return err
+				}
+			}
+		}
+		claimed[dest] = true
+		entry.Dest = dest
+	}
+	return followPrimaries(plan, opts, claimed)
+}
+
+// flatName turns a relative path into a single file name:
+// 100CANON/IMG_0001.JPG becomes 100CANON_IMG_0001.JPG.
+func flatName(rel string) string {
+	return strings.ReplaceAll(filepath.ToSlash(rel), "/", "_")
+}
+
+// flattenSummary describes a flatten plan for the preview.
+func flattenSummary(plan []FilePreview) string {
+	folders := make(map[string]bool)
+	renamed := 0
+	for _, file := range plan {
+		if file.excluded() {
+			continue
+		}
+		folders[file.Category] = true
+		if file.Action == actionRename {
+			renamed++
+		}
+	}
+	summary := fmt.Sprintf("Dateien aus %d Ordnern werden zusammengeführt", len(folders))
+	if renamed > 0 {
+		summary += fmt.Sprintf(", %d davon umbenannt", renamed)
+	}
+	return summary
+}
@@ -0,0 +1,133 @@
+package organizer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenTargets(t *testing.T) {
+	tree := []string{
+		"doc.pdf",
+		"c_x.txt",
+		"100CANON/IMG_0001.JPG",
+		"101CANON/IMG_0001.JPG",
+		"101CANON/IMG_0001.xmp",
+		"102CANON/IMG_0001.JPG",
+		"a/b/x.txt",
+		"c/x.txt",
+		"sub/doc.pdf",
+	}
+	tests := []struct {
+		name    string
+		naming  FlattenNaming
+		exclude string
+		want    map[string]string
+	}{
+		{
+			name:   "Ordnerpfad voranstellen",
+			naming: FlattenPrefix,
+			want: map[string]string{
+				"100CANON/IMG_0001.JPG": "IMG_0001.JPG",
+				"101CANON/IMG_0001.JPG": "101CANON_IMG_0001.JPG",
+				"101CANON/IMG_0001.xmp": "101CANON_IMG_0001.xmp",
+				"102CANON/IMG_0001.JPG": "102CANON_IMG_0001.JPG",
+				"a/b/x.txt":             "x.txt",
+				// The prefixed name is taken as well.
+				"c/x.txt":     "c_x (2).txt",
+				"sub/doc.pdf": "sub_doc.pdf",
+			},
+		},
+		{
+			name:   "Zähler anhängen",
+			naming: FlattenCounter,
+			want: map[string]string{
+				"100CANON/IMG_0001.JPG": "IMG_0001.JPG",
+				"101CANON/IMG_0001.JPG": "IMG_0001 (2).JPG",
+				"101CANON/IMG_0001.xmp": "IMG_0001 (2).xmp",
+				"102CANON/IMG_0001.JPG": "IMG_0001 (3).JPG",
+				"a/b/x.txt":             "x.txt",
+				"c/x.txt":               "x (2).txt",
+				"sub/doc.pdf":           "doc (2).pdf",
+			},
+		},
+		{
+			name:    "ausgeschlossene Datei gibt den Namen frei",
+			naming:  FlattenCounter,
+			exclude: "100CANON/IMG_0001.JPG",
+			want: map[string]string{
+				"101CANON/IMG_0001.JPG": "IMG_0001.JPG",
+				"101CANON/IMG_0001.xmp": "IMG_0001.xmp",
+				"102CANON/IMG_0001.JPG": "IMG_0001 (2).JPG",
+				"a/b/x.txt":             "x.txt",
+				"c/x.txt":               "x (2).txt",
+				"sub/doc.pdf":           "doc (2).pdf",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			for _, name := range tree {
+				writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), name)
+			}
+			opts := DefaultOptions()
+			opts.Flatten = true
+			opts.Recursive = true
+			opts.MaxDepth = 0
+			opts.FlattenNaming = tt.naming
+
+			plan, _, busy, err := buildFlattenPlan([]string{dir}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(busy) > 0 {
+				t.Fatalf("Dateien in Benutzung: %+v", busy)
+			}
+			if tt.exclude != "" {
+				for i := range plan {
+					plan[i].Excluded = filepath.ToSlash(plan[i].Rel) == tt.exclude
+				}
+				if err := flattenTargets(plan, opts); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := make(map[string]string)
+			for _, file := range plan {
+				rel := filepath.ToSlash(file.Rel)
+				if file.excluded() {
+					continue
+				}
+				if filepath.Dir(file.Dest) != dir {
+					t.Errorf("%s: Ziel %s liegt nicht im Zielordner", rel, file.Dest)
+				}
+				got[rel] = filepath.Base(file.Dest)
+				// Only files that had to give way are renamed.
+				if renamed := filepath.Base(file.Dest) != file.Name; renamed != (file.Action == actionRename) && file.CompanionOf == "" {
+					t.Errorf("%s: %v für %s", rel, file.Action, filepath.Base(file.Dest))
+				}
+				if want := filepath.Dir(rel); file.Category != want {
+					t.Errorf("%s: Ordner %s, erwartet %s", rel, file.Category, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ziele = %v\nerwartet %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlatName(t *testing.T) {
+	tests := map[string]string{
+		"a.txt":                 "a.txt",
+		"100CANON/IMG_0001.JPG": "100CANON_IMG_0001.JPG",
+		"a/b/c.tar.gz":          "a_b_c.tar.gz",
+	}
+	for rel, want := range tests {
+		if got := flatName(filepath.FromSlash(rel)); got != want {
+			t.Errorf("flatName(%q) = %q, erwartet %q", rel, got, want)
+		}
+	}
+}
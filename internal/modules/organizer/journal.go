@@ -0,0 +1,593 @@
+package organizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Journal records everything a single organize run changed on disk so the
+// run can be reverted later, even from another session. It is stored as one
+// JSON object per line, appended while the run progresses. Dirs are the
+// folders the run created, Removed the ones the cleanup after it removed.
+type Journal struct {
+	ID       string
+	Root     string
+	Started  time.Time
+	Moves    []JournalMove
+	Dirs     []string
+	Bundles  []JournalBundle
+	Removed  []string
+	Reverted *time.Time
+
+	path string
+	file *os.File
+}
+
+// JournalMove is a single file operation. Overwrite is set when the move
+// replaced an existing file, Dropped when the source was removed because the
+// destination already held identical content, Link when Dst is only a link
+// to Src; Size and ModTime always describe the file at Dst after the
+// operation. For a file packed into an archive bundle Dst is the bundle,
+// Entry the name inside it, and Size and ModTime describe the original.
+// Pruned is set when Dst was a link of an earlier run to Src that was removed
+// because Src was gone or another link replaced it, Hard when that link was a
+// hardlink. Restored is set once a revert has undone the operation.
+type JournalMove struct {
+	Src        string
+	Dst        string
+	Size       int64
+	ModTime    time.Time
+	SrcModTime time.Time
+	Overwrite  bool
+	Dropped    bool
+	Link       bool
+	Entry      string
+	Pruned     bool
+	Hard       bool
+	Restored   bool
+}
+
+// JournalBundle is an archive created by a run. It is deleted on revert once
+// all of its files are restored, unless it was changed in the meantime.
+type JournalBundle struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+type journalEntry struct {
+	Type    string    `json:"type"`
+	Root    string    `json:"root,omitempty"`
+	Time    time.Time `json:"time,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Src     string    `json:"src,omitempty"`
+	Dst     string    `json:"dst,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mtime,omitempty"`
+	Entry   string    `json:"entry,omitempty"`
+
+	SrcModTime time.Time `json:"src_mtime,omitempty"`
+	Overwrite  bool      `json:"overwrite,omitempty"`
+	Hard       bool      `json:"hard,omitempty"`
+	// Index is the position of the operation a "restored" entry refers to.
+	Index int `json:"index,omitempty"`
+}
+
+type RevertProblem struct {
+	Path   string
+	Reason string
+}
+
+// RevertResult sums up a revert. Partial is set when operations are left
+// that could not be undone; the run then stays in the history and can be
+// reverted again once the problems are solved.
+type RevertResult struct {
+	Restored int
+	Problems []RevertProblem
+	Partial  bool
+}
+
+func journalDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journal"), nil
+}
+
+func newJournal(root string) (*Journal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	j := &Journal{
+		ID:      now.Format("20060102-150405.000000"),
+		Root:    absRoot,
+		Started: now,
+	}
+	j.path = filepath.Join(dir, j.ID+".jsonl")
+
+	j.file, err = os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.append(journalEntry{Type: "run", Root: j.Root, Time: j.Started}); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) append(e journalEntry) error {
+	if j.file == nil {
+		f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeJournalEntry(f, e)
+	}
+	return writeJournalEntry(j.file, e)
+}
+
+func writeJournalEntry(f *os.File, e journalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (j *Journal) recordDir(path string) error {
+	j.Dirs = append(j.Dirs, path)
+	return j.append(journalEntry{Type: "mkdir", Path: path})
+}
+
+// recordRemovedDir records a folder removed by the cleanup after a run.
+func (j *Journal) recordRemovedDir(path string) error {
+	j.Removed = append(j.Removed, path)
+	return j.append(journalEntry{Type: "rmdir", Path: path})
+}
+
+// recordMove records a file moved from src to dst; info describes the file at
+// dst after the move.
+func (j *Journal) recordMove(src, dst string, info os.FileInfo, overwrite bool) error {
+	move := JournalMove{Src: src, Dst: dst, Size: info.Size(), ModTime: info.ModTime(), Overwrite: overwrite}
+	j.Moves = append(j.Moves, move)
+	return j.append(journalEntry{Type: "move", Src: src, Dst: dst, Size: move.Size, ModTime: move.ModTime, Overwrite: overwrite})
+}
+
+func (j *Journal) recordLink(src, dst string, info os.FileInfo) error {
+	move := JournalMove{Src: src, Dst: dst, Size: info.Size(), ModTime: info.ModTime(), Link: true}
+	j.Moves = append(j.Moves, move)
+	return j.append(journalEntry{Type: "link", Src: src, Dst: dst, Size: move.Size, ModTime: move.ModTime})
+}
+
+func (j *Journal) recordBundle(path string, info os.FileInfo) error {
+	bundle := JournalBundle{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+	j.Bundles = append(j.Bundles, bundle)
+	return j.append(journalEntry{Type: "bundle", Path: path, Size: bundle.Size, ModTime: bundle.ModTime})
+}
+
+func (j *Journal) recordArchive(src, bundle, entry string, info os.FileInfo) error {
+	move := JournalMove{Src: src, Dst: bundle, Size: info.Size(), ModTime: info.ModTime(), Entry: entry}
+	j.Moves = append(j.Moves, move)
+	return j.append(journalEntry{Type: "archive", Src: src, Dst: bundle, Entry: entry, Size: move.Size, ModTime: move.ModTime})
+}
+
+func (j *Journal) recordDrop(src, dst string, srcInfo, dstInfo os.FileInfo) error {
+	move := JournalMove{
+		Src:        src,
+		Dst:        dst,
+		Size:       dstInfo.Size(),
+		ModTime:    dstInfo.ModTime(),
+		SrcModTime: srcInfo.ModTime(),
+		Dropped:    true,
+	}
+	j.Moves = append(j.Moves, move)
+	return j.append(journalEntry{Type: "drop", Src: src, Dst: dst, Size: move.Size, ModTime: move.ModTime, SrcModTime: move.SrcModTime})
+}
+
+func (j *Journal) recordPrune(link JournalMove) error {
+	link.Pruned = true
+	j.Moves = append(j.Moves, link)
+	return j.append(journalEntry{Type: "prune", Src: link.Src, Dst: link.Dst, Size: link.Size, ModTime: link.ModTime, Hard: link.Hard})
+}
+
+// recordRestored marks the operation at index i as undone, so a revert that
+// is tried again skips it.
+func (j *Journal) recordRestored(i int) error {
+	j.Moves[i].Restored = true
+	return j.append(journalEntry{Type: "restored", Index: i})
+}
+
+// partlyReverted reports whether a revert undid some of the run but not all
+// of it.
+func (j Journal) partlyReverted() bool {
+	if j.Reverted != nil {
+		return false
+	}
+	for _, move := range j.Moves {
+		if move.Restored {
+			return true
+		}
+	}
+	return false
+}
+
+// junkDir is the folder junk files removed after the run are kept in until
+// the run is reverted.
+func (j *Journal) junkDir() string {
+	return strings.TrimSuffix(j.path, ".jsonl") + "-junk"
+}
+
+// reopen writes the journal file again if Close removed it because the run
+// changed nothing, so a later cleanup can still be recorded.
+func (j *Journal) reopen() error {
+	if j.file != nil {
+		return nil
+	}
+	if _, err := os.Stat(j.path); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeJournalEntry(f, journalEntry{Type: "run", Root: j.Root, Time: j.Started})
+}
+
+// Close closes the journal file. An empty journal is removed, since a run
+// that changed nothing cannot be reverted.
+func (j *Journal) Close() error {
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	if len(j.Moves) == 0 && len(j.Dirs) == 0 && len(j.Bundles) == 0 && len(j.Removed) == 0 {
+		return os.Remove(j.path)
+	}
+	return err
+}
+
+func readJournal(path string) (Journal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Journal{}, err
+	}
+	defer f.Close()
+
+	j := Journal{
+		ID:   strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		path: path,
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A crash while appending may leave a truncated last line.
+			continue
+		}
+		switch e.Type {
+		case "run":
+			j.Root = e.Root
+			j.Started = e.Time
+		case "mkdir":
+			j.Dirs = append(j.Dirs, e.Path)
+		case "rmdir":
+			j.Removed = append(j.Removed, e.Path)
+		case "bundle":
+			j.Bundles = append(j.Bundles, JournalBundle{Path: e.Path, Size: e.Size, ModTime: e.ModTime})
+		case "move", "drop", "link", "archive", "prune":
+			j.Moves = append(j.Moves, JournalMove{
+				Src:        e.Src,
+				Dst:        e.Dst,
+				Size:       e.Size,
+				ModTime:    e.ModTime,
+				SrcModTime: e.SrcModTime,
+				Overwrite:  e.Overwrite,
+				Dropped:    e.Type == "drop",
+				Link:       e.Type == "link",
+				Entry:      e.Entry,
+				Pruned:     e.Type == "prune",
+				Hard:       e.Hard,
+			})
+		case "restored":
+			if e.Index >= 0 && e.Index < len(j.Moves) {
+				j.Moves[e.Index].Restored = true
+			}
+		case "revert":
+			t := e.Time
+			j.Reverted = &t
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Journal{}, err
+	}
+	if j.Root == "" {
+		return Journal{}, fmt.Errorf("ungültiges Journal: %s", filepath.Base(path))
+	}
+	return j, nil
+}
+
+// LoadJournals returns all recorded runs, newest first.
+func LoadJournals() ([]Journal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var journals []Journal
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+		j, err := readJournal(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		journals = append(journals, j)
+	}
+
+	sort.Slice(journals, func(a, b int) bool {
+		return journals[a].Started.After(journals[b].Started)
+	})
+	return journals, nil
+}
+
+// Revert moves every file of the run back to where it came from, extracts
+// archived files from their bundles, removes the links of a link run and puts
+// back the links it pruned or replaced. Files that were deleted or modified
+// since the run, or whose original location is occupied again, are left
+// alone and reported as problems. Every undone operation is recorded, so a
+// revert with problems can be tried again later and only the operations left
+// over are undone then; the run counts as reverted once none are left.
+func Revert(j *Journal) (RevertResult, error) {
+	var result RevertResult
+
+	if j.Reverted != nil {
+		return result, fmt.Errorf("Dieser Lauf wurde bereits rückgängig gemacht.")
+	}
+
+	// Archived files are restored per bundle after the loop, so every
+	// bundle is only read once.
+	archived := make(map[string][]int)
+
+	// Folders the cleanup removed come back first; the files that were
+	// in them are moved back below.
+	for i := len(j.Removed) - 1; i >= 0; i-- {
+		if err := os.MkdirAll(j.Removed[i], os.ModePerm); err != nil {
+			result.Problems = append(result.Problems, RevertProblem{Path: j.Removed[i], Reason: err.Error()})
+		}
+	}
+
+	for i := len(j.Moves) - 1; i >= 0; i-- {
+		move := j.Moves[i]
+		if move.Restored {
+			continue
+		}
+
+		if move.Entry != "" {
+			archived[move.Dst] = append(archived[move.Dst], i)
+			continue
+		}
+		if move.Pruned {
+			if reason := restoreLink(move); reason != "" {
+				result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: reason})
+				continue
+			}
+			if err := j.recordRestored(i); err != nil {
+				return result, err
+			}
+			result.Restored++
+			continue
+		}
+		if move.Link {
+			// The original never moved; only the link goes away.
+			if err := removeLink(move.Src, move.Dst); errors.Is(err, os.ErrNotExist) {
+				result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: "wurde gelöscht"})
+				continue
+			} else if err != nil {
+				result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: err.Error()})
+				continue
+			}
+			if err := j.recordRestored(i); err != nil {
+				return result, err
+			}
+			result.Restored++
+			continue
+		}
+
+		info, err := os.Stat(move.Dst)
+		if errors.Is(err, os.ErrNotExist) {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: "wurde gelöscht"})
+			continue
+		}
+		if err != nil {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: err.Error()})
+			continue
+		}
+		if info.Size() != move.Size || !info.ModTime().Equal(move.ModTime) {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: "wurde seitdem verändert"})
+			continue
+		}
+		if _, err := os.Lstat(move.Src); err == nil {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Src, Reason: "Ursprungspfad ist wieder belegt"})
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(move.Src), os.ModePerm); err != nil {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Src, Reason: err.Error()})
+			continue
+		}
+		if move.Dropped {
+			// The source was identical to the file already at Dst, which
+			// stays where it is; restore the removed copy.
+			if err := copyFile(move.Dst, move.Src); err != nil {
+				result.Problems = append(result.Problems, RevertProblem{Path: move.Src, Reason: err.Error()})
+				continue
+			}
+			os.Chtimes(move.Src, move.SrcModTime, move.SrcModTime)
+			if err := j.recordRestored(i); err != nil {
+				return result, err
+			}
+			result.Restored++
+			continue
+		}
+
+		if err := moveFile(move.Dst, move.Src); err != nil {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: err.Error()})
+			continue
+		}
+		if move.Overwrite {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Dst, Reason: "überschriebene Datei kann nicht wiederhergestellt werden"})
+		}
+		if err := j.recordRestored(i); err != nil {
+			return result, err
+		}
+		result.Restored++
+	}
+
+	for _, bundle := range j.Bundles {
+		pending := archived[bundle.Path]
+		if len(pending) == 0 {
+			continue
+		}
+		moves := make([]JournalMove, len(pending))
+		for k, i := range pending {
+			moves[k] = j.Moves[i]
+		}
+		restored, complete := restoreBundle(bundle, moves, &result)
+		for _, i := range pending {
+			if !restored[j.Moves[i].Entry] {
+				continue
+			}
+			if err := j.recordRestored(i); err != nil {
+				return result, err
+			}
+		}
+		if complete {
+			os.Remove(bundle.Path)
+		}
+	}
+
+	// Only directories that are empty again are removed; anything the user
+	// put there in the meantime stays.
+	for i := len(j.Dirs) - 1; i >= 0; i-- {
+		os.Remove(j.Dirs[i])
+	}
+	os.Remove(j.junkDir())
+
+	for _, move := range j.Moves {
+		if !move.Restored {
+			result.Partial = true
+			return result, nil
+		}
+	}
+	now := time.Now()
+	if err := j.append(journalEntry{Type: "revert", Time: now}); err != nil {
+		return result, err
+	}
+	j.Reverted = &now
+
+	return result, nil
+}
+
+// restoreLink puts a pruned link back and returns why it could not. A
+// hardlink was the last copy of its data, so it can only be linked again
+// once its original is back.
+func restoreLink(move JournalMove) string {
+	if _, err := os.Lstat(move.Dst); err == nil {
+		return "Pfad ist wieder belegt"
+	}
+	if err := os.MkdirAll(filepath.Dir(move.Dst), os.ModePerm); err != nil {
+		return err.Error()
+	}
+	if !move.Hard {
+		abs, err := filepath.Abs(move.Src)
+		if err == nil {
+			err = os.Symlink(abs, move.Dst)
+		}
+		if err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	if _, err := os.Stat(move.Src); errors.Is(err, os.ErrNotExist) {
+		return "Hardlink kann ohne Original nicht wiederhergestellt werden"
+	}
+	if err := os.Link(move.Src, move.Dst); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// restoreBundle extracts the archived files of a run from bundle. It returns
+// the entries restored and whether the bundle can be deleted: it must be
+// unchanged and every file must have been restored.
+func restoreBundle(bundle JournalBundle, moves []JournalMove, result *RevertResult) (map[string]bool, bool) {
+	info, err := os.Stat(bundle.Path)
+	if err != nil {
+		for _, move := range moves {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Src, Reason: "Archiv " + filepath.Base(bundle.Path) + " fehlt"})
+		}
+		return nil, false
+	}
+
+	targets := make(map[string]string)
+	for _, move := range moves {
+		if _, err := os.Lstat(move.Src); err == nil {
+			result.Problems = append(result.Problems, RevertProblem{Path: move.Src, Reason: "Ursprungspfad ist wieder belegt"})
+			continue
+		}
+		targets[move.Entry] = move.Src
+	}
+
+	restored, err := extractEntries(bundle.Path, targets)
+	if err != nil {
+		for _, src := range targets {
+			result.Problems = append(result.Problems, RevertProblem{Path: src, Reason: err.Error()})
+		}
+		return nil, false
+	}
+	for entry, src := range targets {
+		if !restored[entry] {
+			result.Problems = append(result.Problems, RevertProblem{Path: src, Reason: "im Archiv beschädigt oder nicht vorhanden"})
+		}
+	}
+	result.Restored += len(restored)
+
+	return restored, len(restored) == len(moves) && info.Size() == bundle.Size && info.ModTime().Equal(bundle.ModTime)
+}
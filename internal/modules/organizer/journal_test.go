@@ -0,0 +1,201 @@
+package organizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// organizeForTest sorts a folder with a.jpg and b.pdf and returns the
+// journal of the run.
+func organizeForTest(t *testing.T, dir string) *Journal {
+	t.Helper()
+	writeFile(t, filepath.Join(dir, "a.jpg"), "bild")
+	writeFile(t, filepath.Join(dir, "b.pdf"), "dokument")
+
+	opts := DefaultOptions()
+	plan, _, _, err := buildPlan([]string{dir}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, journal, err := executePlan(context.Background(), dir, plan, nil, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalMoved != 2 {
+		t.Fatalf("%d Dateien verschoben, erwartet 2", stats.TotalMoved)
+	}
+	return journal
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	journal := organizeForTest(t, dir)
+
+	read, err := readJournal(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Root != journal.Root || !read.Started.Equal(journal.Started) {
+		t.Errorf("Lauf %s %v gelesen, geschrieben %s %v", read.Root, read.Started, journal.Root, journal.Started)
+	}
+	if !reflect.DeepEqual(read.Dirs, journal.Dirs) {
+		t.Errorf("Ordner %v gelesen, geschrieben %v", read.Dirs, journal.Dirs)
+	}
+	if len(read.Moves) != len(journal.Moves) {
+		t.Fatalf("%d Einträge gelesen, geschrieben %d", len(read.Moves), len(journal.Moves))
+	}
+	for i, move := range read.Moves {
+		want := journal.Moves[i]
+		if move.Src != want.Src || move.Dst != want.Dst || move.Size != want.Size || !move.ModTime.Equal(want.ModTime) {
+			t.Errorf("Eintrag %d: %+v gelesen, geschrieben %+v", i, move, want)
+		}
+		// The journal describes the file as it is at the destination.
+		info, err := os.Stat(move.Dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != move.Size || !info.ModTime().Equal(move.ModTime) {
+			t.Errorf("%s: Journal %d Bytes %v, Ziel %d Bytes %v", move.Dst, move.Size, move.ModTime, info.Size(), info.ModTime())
+		}
+	}
+
+	journals, err := LoadJournals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journals) != 1 || journals[0].ID != journal.ID {
+		t.Errorf("LoadJournals = %v, erwartet den Lauf %s", journals, journal.ID)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T, dir string)
+		restored int
+		problems []string
+		// fix solves the problem, so a second revert restores the rest;
+		// nil if it cannot be solved.
+		fix func(t *testing.T, dir string, journal *Journal)
+	}{
+		{
+			name:     "unverändert",
+			prepare:  func(t *testing.T, dir string) {},
+			restored: 2,
+		},
+		{
+			name: "Datei seitdem verändert",
+			prepare: func(t *testing.T, dir string) {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(filepath.Join(dir, "Bilder", "a.jpg"), later, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+			restored: 1,
+			problems: []string{"wurde seitdem verändert"},
+			fix: func(t *testing.T, dir string, journal *Journal) {
+				for _, move := range journal.Moves {
+					if filepath.Base(move.Dst) == "a.jpg" {
+						if err := os.Chtimes(move.Dst, move.ModTime, move.ModTime); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+			},
+		},
+		{
+			name: "Datei gelöscht",
+			prepare: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, "Dokumente", "b.pdf")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			restored: 1,
+			problems: []string{"wurde gelöscht"},
+		},
+		{
+			name: "Ursprung wieder belegt",
+			prepare: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "a.jpg"), "neu")
+			},
+			restored: 1,
+			problems: []string{"Ursprungspfad ist wieder belegt"},
+			fix: func(t *testing.T, dir string, journal *Journal) {
+				if err := os.Remove(filepath.Join(dir, "a.jpg")); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			journal := organizeForTest(t, dir)
+			tt.prepare(t, dir)
+
+			result, err := Revert(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var problems []string
+			for _, problem := range result.Problems {
+				problems = append(problems, problem.Reason)
+			}
+			if result.Restored != tt.restored || !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("Revert = %d wiederhergestellt, Probleme %q; erwartet %d, %q", result.Restored, problems, tt.restored, tt.problems)
+			}
+			if tt.problems == nil {
+				for _, name := range []string{"a.jpg", "b.pdf"} {
+					if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+						t.Errorf("%s nicht zurückgeholt: %v", name, err)
+					}
+				}
+				for _, name := range []string{"Bilder", "Dokumente"} {
+					if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+						t.Errorf("Ordner %s wurde nicht entfernt", name)
+					}
+				}
+			}
+
+			read, err := readJournal(journal.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.problems != nil {
+				// A partial revert stays open and only the rest is
+				// undone by the next one.
+				if !result.Partial || read.Reverted != nil || !read.partlyReverted() {
+					t.Fatalf("teilweises Rückgängigmachen als abgeschlossen vermerkt: %+v", result)
+				}
+				if tt.fix == nil {
+					return
+				}
+				tt.fix(t, dir, &read)
+				result, err = Revert(&read)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Restored != 1 || len(result.Problems) > 0 || result.Partial {
+					t.Fatalf("zweites Rückgängigmachen = %+v, erwartet 1 Datei ohne Probleme", result)
+				}
+				if read, err = readJournal(journal.path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The finished revert is recorded, another one is refused.
+			if read.Reverted == nil {
+				t.Error("Rückgängig machen nicht im Journal vermerkt")
+			}
+			if _, err := Revert(&read); err == nil {
+				t.Error("zweites Rückgängig machen wurde nicht abgelehnt")
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package organizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// minConfidence is the probability a learned category needs before it is
+// suggested instead of the fallback category.
+const minConfidence = 0.5
+
+// Classifier is a naive Bayes model that learns categories from the changes
+// made in the preview. It is stored in classifier.json in the config
+// directory and only consulted for files the rules put into the fallback
+// category.
+type Classifier struct {
+	// Examples counts the corrections per category path, Features how often
+	// each feature occurred in them.
+	Examples map[string]int            `json:"examples"`
+	Features map[string]map[string]int `json:"features"`
+}
+
+func classifierPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "classifier.json"), nil
+}
+
+// LoadClassifier reads the learned model. Without one an empty model is
+// returned; on error the empty model is returned together with the error.
+func LoadClassifier() (*Classifier, error) {
+	c := &Classifier{}
+	path, err := classifierPath()
+	if err != nil {
+		return c, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return &Classifier{}, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return c, nil
+}
+
+// Save writes the model to the config directory.
+func (c *Classifier) Save() error {
+	path, err := classifierPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".classifier-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Export writes the model as JSON to w.
+func (c *Classifier) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// ResetClassifier forgets everything learned so far.
+func ResetClassifier() error {
+	path, err := classifierPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Size returns the number of corrections the model was trained with.
+func (c *Classifier) Size() int {
+	n := 0
+	for _, count := range c.Examples {
+		n += count
+	}
+	return n
+}
+
+// learn adds one correction: a file with these features belongs to
+// category.
+func (c *Classifier) learn(features []string, category string) {
+	if c.Examples == nil {
+		c.Examples = make(map[string]int)
+		c.Features = make(map[string]map[string]int)
+	}
+	c.Examples[category]++
+	if c.Features[category] == nil {
+		c.Features[category] = make(map[string]int)
+	}
+	for _, f := range features {
+		c.Features[category][f]++
+	}
+}
+
+// predict returns the most probable category and its probability. Besides
+// the learned categories an "unknown" category competes that has seen half
+// an example and no features, so a model trained with few corrections stays
+// unsure about files that share little with them.
+func (c *Classifier) predict(features []string) (string, float64, bool) {
+	if c == nil || len(c.Examples) == 0 {
+		return "", 0, false
+	}
+
+	vocabulary := make(map[string]bool)
+	for _, counts := range c.Features {
+		for f := range counts {
+			vocabulary[f] = true
+		}
+	}
+	const unknownExamples = 0.5
+	total := float64(c.Size()) + unknownExamples
+	unseen := float64(len(vocabulary) + 1)
+
+	best, bestScore := "", math.Log(unknownExamples/total)+float64(len(features))*math.Log(1/unseen)
+	scores := []float64{bestScore}
+	for category, examples := range c.Examples {
+		counts := c.Features[category]
+		sum := 0
+		for _, n := range counts {
+			sum += n
+		}
+		score := math.Log(float64(examples) / total)
+		for _, f := range features {
+			score += math.Log(float64(counts[f]+1) / (float64(sum) + unseen))
+		}
+		scores = append(scores, score)
+		if score > bestScore || (score == bestScore && best != "" && category < best) {
+			best, bestScore = category, score
+		}
+	}
+	if best == "" {
+		return "", 0, false
+	}
+
+	// Turn the log scores into a probability relative to the others.
+	var norm float64
+	for _, score := range scores {
+		norm += math.Exp(score - bestScore)
+	}
+	return best, 1 / norm, true
+}
+
+// suggest returns the learned category for a file the rules left in the
+// fallback category, if the model is confident enough.
+func (c *Classifier) suggest(t *Taxonomy, path string, size int64) (Category, float64, bool) {
+	name, confidence, ok := c.predict(fileFeatures(path, size))
+	if !ok || confidence < minConfidence || name == t.fallback.Path {
+		return Category{}, 0, false
+	}
+	for _, cat := range t.flatten() {
+		if cat.Path == name {
+			return cat, confidence, true
+		}
+	}
+	// The category was removed from the configuration since.
+	return Category{}, 0, false
+}
+
+// fileFeatures describes a file for the classifier: the words of its name,
+// its extension, the folder it lies in and its size class.
+func fileFeatures(path string, size int64) []string {
+	base, ext := splitName(filepath.Base(path))
+	features := []string{
+		"ext:" + strings.ToLower(ext),
+		"dir:" + strings.ToLower(filepath.Base(filepath.Dir(path))),
+		"size:" + sizeBucket(size),
+	}
+	words := strings.FieldsFunc(strings.ToLower(base), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool)
+	for _, word := range words {
+		// Numbers and very short fragments say nothing about the content.
+		if len([]rune(word)) < 3 || strings.IndexFunc(word, unicode.IsLetter) < 0 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		features = append(features, "word:"+word)
+	}
+	return features
+}
+
+// learnCorrections trains the model with the files of plan whose category
+// was changed in the preview and saves it. It returns the number of
+// corrections learned.
+func learnCorrections(c *Classifier, plan []FilePreview) (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+	n := 0
+	for _, file := range plan {
+		if !file.Override || file.Category == file.auto {
+			continue
+		}
+		c.learn(fileFeatures(file.Path, file.Size), file.Category)
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, c.Save()
+}
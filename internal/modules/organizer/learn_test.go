@@ -0,0 +1,110 @@
+package organizer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileFeatures(t *testing.T) {
+	path := filepath.Join("Downloads", "Rechnung_2024-03_Telekom.X1.PDF")
+	want := []string{"ext:.pdf", "dir:downloads", "size:klein", "word:rechnung", "word:telekom"}
+	if got := fileFeatures(path, 2048); !reflect.DeepEqual(got, want) {
+		t.Errorf("fileFeatures = %q, erwartet %q", got, want)
+	}
+}
+
+func TestClassifierPredict(t *testing.T) {
+	// trained learns each file name count times for its category.
+	trained := func(examples map[string][]string, count int) *Classifier {
+		c := &Classifier{}
+		for category, names := range examples {
+			for _, name := range names {
+				for i := 0; i < count; i++ {
+					c.learn(fileFeatures(filepath.Join("Downloads", name), 1<<20), category)
+				}
+			}
+		}
+		return c
+	}
+	invoices := map[string][]string{
+		"Dokumente/Rechnungen": {"rechnung_telekom.pdf", "rechnung_strom.pdf", "rechnung_miete.pdf"},
+		"Dokumente/Verträge":   {"vertrag_miete.pdf", "vertrag_handy.pdf"},
+	}
+
+	tests := []struct {
+		name       string
+		classifier *Classifier
+		file       string
+		want       string
+		confident  bool
+	}{
+		{
+			name: "ohne Modell",
+			file: "rechnung_gas.pdf",
+		},
+		{
+			name:       "leeres Modell",
+			classifier: &Classifier{},
+			file:       "rechnung_gas.pdf",
+		},
+		{
+			name:       "bekanntes Wort",
+			classifier: trained(invoices, 3),
+			file:       "rechnung_gas.pdf",
+			want:       "Dokumente/Rechnungen",
+			confident:  true,
+		},
+		{
+			name:       "anderes bekanntes Wort",
+			classifier: trained(invoices, 3),
+			file:       "vertrag_gas.pdf",
+			want:       "Dokumente/Verträge",
+			confident:  true,
+		},
+		{
+			name:       "eine Korrektur reicht nicht für Fremdes",
+			classifier: trained(map[string][]string{"Dokumente/Rechnungen": {"rechnung_telekom.pdf"}}, 1),
+			file:       "urlaubsplanung_gardasee_liste.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence, ok := tt.classifier.predict(fileFeatures(filepath.Join("Downloads", tt.file), 1<<20))
+			if confident := ok && confidence >= minConfidence; confident != tt.confident {
+				t.Fatalf("predict = %q mit %.2f, sicher %v, erwartet %v", got, confidence, confident, tt.confident)
+			}
+			if tt.confident && got != tt.want {
+				t.Errorf("predict = %q, erwartet %q", got, tt.want)
+			}
+			if ok && (confidence <= 0 || confidence > 1) {
+				t.Errorf("Wahrscheinlichkeit %v außerhalb von (0, 1]", confidence)
+			}
+		})
+	}
+}
+
+func TestClassifierSaveLoad(t *testing.T) {
+	useTempConfig(t)
+	c := &Classifier{}
+	c.learn([]string{"ext:.pdf", "word:rechnung"}, "Dokumente/Rechnungen")
+	c.learn([]string{"ext:.pdf", "word:rechnung"}, "Dokumente/Rechnungen")
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadClassifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, c) || loaded.Size() != 2 {
+		t.Errorf("LoadClassifier = %+v, gespeichert %+v", loaded, c)
+	}
+
+	if err := ResetClassifier(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadClassifier()
+	if err != nil || loaded.Size() != 0 {
+		t.Errorf("nach dem Zurücksetzen: %+v, %v", loaded, err)
+	}
+}
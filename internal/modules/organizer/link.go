@@ -0,0 +1,279 @@
+package organizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// OrganizeMode decides whether files are moved or only linked into a
+// category tree in a separate folder, leaving the originals in place.
+type OrganizeMode int
+
+const (
+	ModeMove OrganizeMode = iota
+	ModeSymlink
+	ModeHardlink
+	organizeModeCount
+)
+
+func (m OrganizeMode) String() string {
+	switch m {
+	case ModeSymlink:
+		return "Symlinks anlegen"
+	case ModeHardlink:
+		return "Hardlinks anlegen"
+	default:
+		return "Dateien verschieben"
+	}
+}
+
+func (o Options) linking() bool {
+	return o.Mode == ModeSymlink || o.Mode == ModeHardlink
+}
+
+// linkRoot returns the absolute folder the link tree is built in.
+func (o Options) linkRoot() (string, error) {
+	if strings.TrimSpace(o.LinkTarget) == "" {
+		return "", fmt.Errorf("Bitte einen Zielordner für die Links angeben.")
+	}
+	target, err := expandHome(strings.TrimSpace(o.LinkTarget))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(target)
+}
+
+// isLinkTo reports whether dest already refers to src, either as a symlink
+// or as a hardlink of the same file.
+func isLinkTo(src, dest string) bool {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	destInfo, err := os.Stat(dest)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(srcInfo, destInfo)
+}
+
+// createLink links dest to src. Symlinks use absolute targets so the tree
+// can be moved independently of the originals.
+func createLink(src, dest string, mode OrganizeMode) error {
+	if mode == ModeSymlink {
+		abs, err := filepath.Abs(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(abs, dest)
+	}
+
+	err := os.Link(src, dest)
+	if errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("Hardlinks sind nur innerhalb eines Dateisystems möglich: %w", err)
+	}
+	return err
+}
+
+// removeLink removes a link created by a run if it still points to src.
+func removeLink(src, dest string) error {
+	info, err := os.Lstat(dest)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(dest)
+		if err != nil {
+			return err
+		}
+		if abs, err := filepath.Abs(src); err != nil || target != abs {
+			return fmt.Errorf("zeigt nicht mehr auf %s", src)
+		}
+		return os.Remove(dest)
+	}
+	if !isLinkTo(src, dest) {
+		return fmt.Errorf("ist kein Link auf %s mehr", src)
+	}
+	return os.Remove(dest)
+}
+
+// findDanglingLinks lists links in the link tree whose original is gone:
+// symlinks with a missing target and hardlinks that are the last remaining
+// link to their data. Only links a journal shows ordi created there are
+// considered, so files the user put into the folder are never touched. The
+// returned entries are the journal records of the removals.
+func findDanglingLinks(target string, mode OrganizeMode) ([]JournalMove, error) {
+	created, err := createdLinks(target)
+	if err != nil {
+		return nil, err
+	}
+	var dangling []JournalMove
+	for _, link := range created {
+		if prune, ok := danglingLink(link, mode); ok {
+			dangling = append(dangling, prune)
+		}
+	}
+	sort.Slice(dangling, func(a, b int) bool { return dangling[a].Dst < dangling[b].Dst })
+	return dangling, nil
+}
+
+// createdLinks returns the links below target that runs which were not
+// reverted have created and not pruned or reverted since, by path.
+func createdLinks(target string) (map[string]JournalMove, error) {
+	journals, err := LoadJournals()
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string]JournalMove)
+	seen := make(map[string]bool)
+	// Newest first: a later run that pruned or created the link again wins.
+	for _, j := range journals {
+		if j.Reverted != nil {
+			continue
+		}
+		for i := len(j.Moves) - 1; i >= 0; i-- {
+			move := j.Moves[i]
+			if (!move.Link && !move.Pruned) || seen[move.Dst] || !insideDir(target, move.Dst) {
+				continue
+			}
+			seen[move.Dst] = true
+			// A revert removed the link again or put a pruned one back.
+			switch {
+			case move.Link && !move.Restored:
+				links[move.Dst] = move
+			case move.Pruned && move.Restored:
+				links[move.Dst] = JournalMove{Src: move.Src, Dst: move.Dst, Size: move.Size, ModTime: move.ModTime, Link: true}
+			}
+		}
+	}
+	return links, nil
+}
+
+// replaceableLink reports whether dest is a link a run created and that
+// still refers to its original, so the overwrite policy may replace it. The
+// returned entry records its removal. Anything else at dest, above all a
+// file of the user's own, is never replaced in a link tree.
+func replaceableLink(dest string) (JournalMove, bool, error) {
+	created, err := createdLinks(filepath.Dir(dest))
+	if err != nil {
+		return JournalMove{}, false, err
+	}
+	link, ok := created[dest]
+	if !ok {
+		return JournalMove{}, false, nil
+	}
+	info, err := os.Lstat(dest)
+	if err != nil {
+		return JournalMove{}, false, nil
+	}
+	replaced := JournalMove{Src: link.Src, Dst: link.Dst, Size: link.Size, ModTime: link.ModTime, Pruned: true}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(dest)
+		if err != nil {
+			return JournalMove{}, false, nil
+		}
+		if abs, err := filepath.Abs(link.Src); err != nil || target != abs {
+			return JournalMove{}, false, nil
+		}
+		return replaced, true, nil
+	}
+	if info.Mode().IsRegular() && isLinkTo(link.Src, dest) {
+		replaced.Hard = true
+		return replaced, true, nil
+	}
+	return JournalMove{}, false, nil
+}
+
+// danglingLink checks whether a link a run created is still there, unchanged,
+// and without its original, and returns the record of its removal.
+func danglingLink(link JournalMove, mode OrganizeMode) (JournalMove, bool) {
+	info, err := os.Lstat(link.Dst)
+	if err != nil {
+		return JournalMove{}, false
+	}
+	prune := JournalMove{Src: link.Src, Dst: link.Dst, Size: link.Size, ModTime: link.ModTime, Pruned: true}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(link.Dst)
+		if err != nil {
+			return JournalMove{}, false
+		}
+		if abs, err := filepath.Abs(link.Src); err != nil || target != abs {
+			return JournalMove{}, false
+		}
+		if _, err := os.Stat(link.Dst); errors.Is(err, os.ErrNotExist) {
+			return prune, true
+		}
+		return JournalMove{}, false
+	}
+
+	if mode != ModeHardlink || !info.Mode().IsRegular() {
+		return JournalMove{}, false
+	}
+	// A file with other content or times is no longer the one that was
+	// linked.
+	if info.Size() != link.Size || !info.ModTime().Equal(link.ModTime) {
+		return JournalMove{}, false
+	}
+	if count, ok := linkCount(info); ok && count == 1 {
+		prune.Hard = true
+		return prune, true
+	}
+	return JournalMove{}, false
+}
+
+// pruneLinks removes dangling links, records each removal in journal and
+// removes the folders of the link tree that become empty because of it.
+// Links that changed since they were found are kept.
+func pruneLinks(journal *Journal, target string, links []JournalMove, mode OrganizeMode) (int, error) {
+	removed := 0
+	for _, link := range links {
+		if _, ok := danglingLink(JournalMove{Src: link.Src, Dst: link.Dst, Size: link.Size, ModTime: link.ModTime, Link: true}, mode); !ok {
+			continue
+		}
+		if err := os.Remove(link.Dst); err != nil {
+			return removed, err
+		}
+		if err := journal.recordPrune(link); err != nil {
+			return removed, err
+		}
+		removed++
+		for dir := filepath.Dir(link.Dst); dir != target && strings.HasPrefix(dir, target); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return removed, nil
+}
+
+// RefreshLinks brings the link tree of source up to date: links for new
+// files are added and links whose original is gone are removed. It is what
+// a link run in the TUI does as well.
+func RefreshLinks(source string, opts Options) (CategoryStats, error) {
+	if !opts.linking() {
+		return CategoryStats{}, fmt.Errorf("kein Link-Modus gewählt")
+	}
+	target, err := opts.linkRoot()
+	if err != nil {
+		return CategoryStats{}, err
+	}
+
+	plan, _, _, err := buildPlan([]string{source}, opts)
+	if err != nil {
+		return CategoryStats{}, err
+	}
+	dangling, err := findDanglingLinks(target, opts.Mode)
+	if err != nil {
+		return CategoryStats{}, err
+	}
+	stats, _, err := executePlan(context.Background(), source, plan, dangling, opts, nil)
+	return stats, err
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package organizer
+
+import "os"
+
+// linkCount is not available here, so hardlinks are never considered
+// dangling.
+func linkCount(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
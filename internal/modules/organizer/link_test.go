@@ -0,0 +1,181 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// useTempConfig points the configuration directory, and with it the
+// journals, at a fresh temporary folder.
+func useTempConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+}
+
+// writeFile creates a file an hour old, so scans do not wait for it to
+// settle.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	earlier := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, earlier, earlier); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneLinksKeepsOwnFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not available")
+	}
+	for _, mode := range []OrganizeMode{ModeSymlink, ModeHardlink} {
+		t.Run(mode.String(), func(t *testing.T) {
+			useTempConfig(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			writeFile(t, filepath.Join(source, "bericht.pdf"), "bericht")
+
+			opts := DefaultOptions()
+			opts.Mode = mode
+			opts.LinkTarget = target
+			stats, err := RefreshLinks(source, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.TotalMoved != 1 {
+				t.Fatalf("%d Links angelegt, erwartet 1", stats.TotalMoved)
+			}
+			link := filepath.Join(target, "Dokumente", "bericht.pdf")
+
+			// A file of the user's own, a hardlink with a single link count
+			// and a symlink without target look dangling but were never
+			// created by ordi.
+			own := filepath.Join(target, "Dokumente", "eigene.pdf")
+			writeFile(t, own, "eigene")
+			foreign := filepath.Join(target, "fremd.lnk")
+			if err := os.Symlink(filepath.Join(source, "gibt-es-nicht"), foreign); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(filepath.Join(source, "bericht.pdf")); err != nil {
+				t.Fatal(err)
+			}
+
+			stats, err = RefreshLinks(source, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.PrunedLinks != 1 {
+				t.Errorf("%d Links entfernt, erwartet 1", stats.PrunedLinks)
+			}
+			if _, err := os.Lstat(link); !os.IsNotExist(err) {
+				t.Errorf("verwaister Link %s wurde nicht entfernt", link)
+			}
+			for _, path := range []string{own, foreign} {
+				if _, err := os.Lstat(path); err != nil {
+					t.Errorf("%s wurde entfernt: %v", path, err)
+				}
+			}
+
+			journals, err := LoadJournals()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(journals) == 0 || len(journals[0].Moves) != 1 || !journals[0].Moves[0].Pruned {
+				t.Fatalf("Entfernen nicht im Journal: %+v", journals)
+			}
+			result, err := Revert(&journals[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode == ModeSymlink {
+				if result.Restored != 1 {
+					t.Errorf("Symlink nicht wiederhergestellt: %+v", result)
+				}
+				if _, err := os.Lstat(link); err != nil {
+					t.Errorf("Symlink fehlt nach dem Rückgängigmachen: %v", err)
+				}
+			} else if len(result.Problems) != 1 {
+				t.Errorf("Hardlink ohne Original sollte als Problem gemeldet werden: %+v", result)
+			}
+		})
+	}
+}
+
+func TestLinkOverwriteKeepsOwnFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra rights")
+	}
+	for _, mode := range []OrganizeMode{ModeSymlink, ModeHardlink} {
+		t.Run(mode.String(), func(t *testing.T) {
+			useTempConfig(t)
+			first := t.TempDir()
+			second := t.TempDir()
+			target := t.TempDir()
+			writeFile(t, filepath.Join(first, "bericht.pdf"), "alt")
+			writeFile(t, filepath.Join(second, "bericht.pdf"), "neu")
+			writeFile(t, filepath.Join(second, "notiz.pdf"), "notiz")
+			// A file of the user's own with the name of a planned link.
+			own := filepath.Join(target, "Dokumente", "notiz.pdf")
+			writeFile(t, own, "eigene")
+
+			opts := DefaultOptions()
+			opts.Mode = mode
+			opts.LinkTarget = target
+			opts.Conflict = ConflictOverwrite
+			if _, err := RefreshLinks(first, opts); err != nil {
+				t.Fatal(err)
+			}
+			stats, err := RefreshLinks(second, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Overwritten != 1 || stats.Renamed != 1 {
+				t.Errorf("%d überschrieben, %d umbenannt, erwartet je 1", stats.Overwritten, stats.Renamed)
+			}
+
+			link := filepath.Join(target, "Dokumente", "bericht.pdf")
+			if !isLinkTo(filepath.Join(second, "bericht.pdf"), link) {
+				t.Errorf("%s wurde nicht durch den neuen Link ersetzt", link)
+			}
+			if data, err := os.ReadFile(own); err != nil || string(data) != "eigene" {
+				t.Errorf("eigene Datei überschrieben: %q, %v", data, err)
+			}
+			renamed := filepath.Join(target, "Dokumente", "notiz (2).pdf")
+			if !isLinkTo(filepath.Join(second, "notiz.pdf"), renamed) {
+				t.Errorf("%s ist kein Link auf notiz.pdf", renamed)
+			}
+
+			// Reverting the second run brings back the replaced link.
+			journals, err := LoadJournals()
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Revert(&journals[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Problems) > 0 {
+				t.Errorf("Probleme beim Rückgängigmachen: %+v", result.Problems)
+			}
+			if !isLinkTo(filepath.Join(first, "bericht.pdf"), link) {
+				t.Errorf("alter Link %s nicht wiederhergestellt", link)
+			}
+			if _, err := os.Lstat(renamed); !os.IsNotExist(err) {
+				t.Errorf("%s wurde nicht entfernt", renamed)
+			}
+			if _, err := os.Stat(own); err != nil {
+				t.Errorf("eigene Datei fehlt: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package organizer
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hardlinks of a file.
+func linkCount(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Nlink), true
+}
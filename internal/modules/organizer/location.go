@@ -0,0 +1,181 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"example/ordi/internal/places"
+)
+
+// placeRadiusPresets are the selectable radii in kilometres within which
+// photos count as taken at the same place.
+var placeRadiusPresets = []int{1, 5, 10, 25, 50, 100}
+
+const (
+	// noPlace is the folder for photos without a recorded position.
+	noPlace = "Ohne Ort"
+	// placeMinPhotos is how many photos within the radius make a spot dense
+	// enough to grow a group; isolated photos are named on their own.
+	placeMinPhotos = 3
+)
+
+// placeName names a position after the nearest city of the embedded list.
+func placeName(p places.Point) string {
+	city, _ := places.Nearest(p)
+	return city.String()
+}
+
+// assignPlaces groups the photos with a position by density and names every
+// group after the city nearest to its centre. Without a radius each photo
+// keeps the city nearest to itself.
+func assignPlaces(plan []FilePreview, opts Options) {
+	if opts.PlaceRadius <= 0 {
+		return
+	}
+
+	var photos []int
+	var points []places.Point
+	for i, file := range plan {
+		if file.excluded() || file.CompanionOf != "" || file.vars.gps == nil {
+			continue
+		}
+		photos = append(photos, i)
+		points = append(points, *file.vars.gps)
+	}
+
+	for _, group := range dbscan(points, float64(opts.PlaceRadius), placeMinPhotos) {
+		centre := make([]places.Point, len(group))
+		for j, p := range group {
+			centre[j] = points[p]
+		}
+		name := placeName(places.Centre(centre))
+		for _, p := range group {
+			plan[photos[p]].vars.place = name
+		}
+	}
+}
+
+// dbscan groups points by density: a point with at least minPoints points
+// within radius km (itself included) starts or extends a group, and the
+// points it reaches join that group. Every remaining point forms a group of
+// its own.
+func dbscan(points []places.Point, radius float64, minPoints int) [][]int {
+	// Neighbours are searched in a latitude window, which is cheap to find
+	// in a sorted list; a degree of latitude is about 111 km everywhere.
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return points[order[a]].Lat < points[order[b]].Lat })
+	rank := make([]int, len(points))
+	for r, i := range order {
+		rank[i] = r
+	}
+	window := radius / 111.0
+
+	neighbours := func(i int) []int {
+		var found []int
+		for r := rank[i] - 1; r >= 0 && points[i].Lat-points[order[r]].Lat <= window; r-- {
+			if places.Distance(points[i], points[order[r]]) <= radius {
+				found = append(found, order[r])
+			}
+		}
+		for r := rank[i] + 1; r < len(order) && points[order[r]].Lat-points[i].Lat <= window; r++ {
+			if places.Distance(points[i], points[order[r]]) <= radius {
+				found = append(found, order[r])
+			}
+		}
+		return found
+	}
+
+	const noise = -1
+	labels := make([]int, len(points))
+	groups := 0
+	for i := range points {
+		if labels[i] != 0 {
+			continue
+		}
+		queue := neighbours(i)
+		if len(queue)+1 < minPoints {
+			labels[i] = noise
+			continue
+		}
+		groups++
+		labels[i] = groups
+		for len(queue) > 0 {
+			j := queue[0]
+			queue = queue[1:]
+			if labels[j] == noise {
+				labels[j] = groups
+			}
+			if labels[j] != 0 {
+				continue
+			}
+			labels[j] = groups
+			if more := neighbours(j); len(more)+1 >= minPoints {
+				queue = append(queue, more...)
+			}
+		}
+	}
+
+	result := make([][]int, groups)
+	for i, label := range labels {
+		if label == noise {
+			result = append(result, []int{i})
+			continue
+		}
+		result[label-1] = append(result[label-1], i)
+	}
+	return result
+}
+
+// placePath builds <category>/<place>/<name><ext>, with the event folder in
+// between when photos are also grouped into events.
+func (o Options) placePath(root string, v templateVars) string {
+	dir := filepath.Join(categoryDir(root, v.category), placeFolder(v.place))
+	if o.EventGap > 0 && v.event != "" {
+		dir = filepath.Join(dir, o.eventName(v.event))
+	}
+	return filepath.Join(dir, sanitizeSegment(v.name+v.ext))
+}
+
+// placeFolder makes a place usable as folder name; photos without a
+// position share the noPlace folder.
+func placeFolder(place string) string {
+	return musicSegment(place, noPlace)
+}
+
+// placeRadiusLabel describes the radius setting.
+func placeRadiusLabel(km int) string {
+	if km == 0 {
+		return "Nein"
+	}
+	return fmt.Sprintf("Umkreis %d km", km)
+}
+
+// photoPlaces counts the photos of a plan per place folder, most photos
+// first.
+func photoPlaces(plan []FilePreview, opts Options) ([]string, map[string]int) {
+	counts := make(map[string]int)
+	for _, file := range plan {
+		if file.excluded() || file.CompanionOf != "" || !opts.taxonomy().isPhoto(file.vars.category) {
+			continue
+		}
+		if _, archived := opts.archiveBundle("", file.vars); archived {
+			continue
+		}
+		counts[placeFolder(file.vars.place)]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		if counts[names[a]] != counts[names[b]] {
+			return counts[names[a]] > counts[names[b]]
+		}
+		return names[a] < names[b]
+	})
+	return names, counts
+}
@@ -0,0 +1,108 @@
+package organizer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"example/ordi/internal/places"
+)
+
+func TestDBSCAN(t *testing.T) {
+	rome := places.Point{Lat: 41.9028, Lon: 12.4964}
+	berlin := places.Point{Lat: 52.52, Lon: 13.405}
+	// near moves a point north by km kilometres.
+	near := func(p places.Point, km float64) places.Point {
+		return places.Point{Lat: p.Lat + km/111.2, Lon: p.Lon}
+	}
+
+	tests := []struct {
+		name   string
+		points []places.Point
+		radius float64
+		want   [][]int
+	}{
+		{
+			name: "keine Punkte",
+		},
+		{
+			name:   "dichte Gruppe und Einzelpunkt",
+			points: []places.Point{rome, near(rome, 0.5), berlin, near(rome, 0.8)},
+			radius: 1,
+			want:   [][]int{{0, 1, 3}, {2}},
+		},
+		{
+			name:   "zu wenige Punkte für eine Gruppe",
+			points: []places.Point{rome, near(rome, 0.5)},
+			radius: 1,
+			want:   [][]int{{0}, {1}},
+		},
+		{
+			name: "Kette wächst über den Umkreis hinaus",
+			points: []places.Point{
+				rome, near(rome, 0.8), near(rome, 1.6), near(rome, 2.4), near(rome, 3.2),
+			},
+			radius: 1,
+			want:   [][]int{{0, 1, 2, 3, 4}},
+		},
+		{
+			name: "gleiche Breite, weit auseinander",
+			points: []places.Point{
+				{Lat: 40, Lon: 0}, {Lat: 40.001, Lon: 0}, {Lat: 40.002, Lon: 0},
+				{Lat: 40, Lon: 20}, {Lat: 40.001, Lon: 20}, {Lat: 40.002, Lon: 20},
+			},
+			radius: 5,
+			want:   [][]int{{0, 1, 2}, {3, 4, 5}},
+		},
+		{
+			name:   "größerer Umkreis fasst zusammen",
+			points: []places.Point{rome, near(rome, 0.5), near(rome, 0.8), near(rome, 30)},
+			radius: 50,
+			want:   [][]int{{0, 1, 2, 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dbscan(tt.points, tt.radius, placeMinPhotos)
+			for _, group := range got {
+				sort.Ints(group)
+			}
+			sort.Slice(got, func(a, b int) bool { return got[a][0] < got[b][0] })
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dbscan = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignPlaces(t *testing.T) {
+	rome := places.Point{Lat: 41.9028, Lon: 12.4964}
+	// A photo on the outskirts is named after the group, not after the
+	// city nearest to itself.
+	outskirts := places.Point{Lat: 41.95, Lon: 12.5}
+	plan := []FilePreview{
+		{Name: "a.jpg", vars: templateVars{gps: &rome}},
+		{Name: "b.jpg", vars: templateVars{gps: &rome}},
+		{Name: "c.jpg", vars: templateVars{gps: &outskirts}},
+		{Name: "ohne.jpg"},
+		{Name: "weg.jpg", Excluded: true, vars: templateVars{gps: &rome}},
+	}
+	opts := DefaultOptions()
+	opts.PlaceRadius = 10
+	assignPlaces(plan, opts)
+
+	want := placeName(places.Centre([]places.Point{rome, rome, outskirts}))
+	for _, file := range plan[:3] {
+		if file.vars.place != want {
+			t.Errorf("%s: Ort %q, erwartet %q", file.Name, file.vars.place, want)
+		}
+	}
+	for _, file := range plan[3:] {
+		if file.vars.place != "" {
+			t.Errorf("%s: Ort %q, erwartet keinen", file.Name, file.vars.place)
+		}
+	}
+}
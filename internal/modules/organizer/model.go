@@ -1,9 +1,15 @@
 package organizer
 
 import (
+	"fmt"
+	"os"
+
+	"example/ordi/internal/inuse"
 	"example/ordi/internal/modules/organizer/styles"
 
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -11,17 +17,27 @@ import (
 type ScanCompleteMsg struct {
 	Files      []FilePreview
 	TotalFiles int
+	Ignored    int
+	Busy       []inuse.File
+	Dangling   []JournalMove
 	Err        error
 }
 
 type OrganizeProgressMsg struct {
-	Current int
-	Total   int
+	Current    int
+	Total      int
+	File       string
+	BytesMoved int64
+	BytesTotal int64
 }
 
+// OrganizeCompleteMsg ends a run. Journal is set when files were already
+// moved, so an aborted or failed run can be rolled back.
 type OrganizeCompleteMsg struct {
-	Stats CategoryStats
-	Err   error
+	Stats   CategoryStats
+	Journal *Journal
+	Aborted bool
+	Err     error
 }
 
 type ProcessSuccessMsg struct{ Path string }
@@ -32,6 +48,39 @@ type ProcessErrorMsg struct{ Err error }
 
 type BackMsg struct{}
 
+type HistoryLoadedMsg struct {
+	Journals []Journal
+	Err      error
+}
+
+type RevertCompleteMsg struct {
+	Result RevertResult
+	Err    error
+}
+
+// EmptyDirsMsg lists the folders that are empty after a run.
+type EmptyDirsMsg struct {
+	Dirs []emptyDir
+	Err  error
+}
+
+type CleanupCompleteMsg struct {
+	Removed int
+	Err     error
+}
+
+// WatchEventMsg carries one entry of the watch log.
+type WatchEventMsg struct{ Event WatchEvent }
+
+// WatchStoppedMsg is sent once watching has ended.
+type WatchStoppedMsg struct{ Err error }
+
+// PlanEditedMsg is sent when the editor with the plan file was closed.
+type PlanEditedMsg struct {
+	Path string
+	Err  error
+}
+
 type state int
 
 const (
@@ -40,18 +89,77 @@ const (
 	statePreview
 	stateOrganizing
 	stateFinished
+	stateOptions
+	stateAborted
+	stateHistory
+	stateReverting
+	stateReverted
+	stateWatchInput
+	stateWatching
+	stateCleanup
 )
 
+// FilePreview is one entry of the plan: the file at Path goes to Dest.
+// Override marks a category chosen by the user; Excluded and ExtExcluded
+// keep the file (or all files with its extension) out of the run.
 type FilePreview struct {
 	Name     string
+	Source   string
+	Path     string
+	Rel      string
+	Depth    int
 	Category string
 	Icon     string
 	Size     int64
+	Dest     string
+	Action   conflictAction
+	Detected string
+	Mismatch bool
+	// Suggested is the confidence of a category learned from earlier
+	// corrections; 0 if the rules decided.
+	Suggested   float64
+	Override    bool
+	Excluded    bool
+	ExtExcluded bool
+	// ClashWith is the file from another source that wants the same name;
+	// KeepBoth is the user's decision to keep both under different names.
+	ClashWith string
+	KeepBoth  bool
+	// CompanionOf is the primary file of a sidecar such as an .xmp; the
+	// sidecar goes wherever that file goes.
+	CompanionOf string
+
+	vars   templateVars
+	target string
+	// auto is the category chosen before any change in the preview.
+	auto string
+	// manual is a destination typed in the plan editor; it replaces the
+	// one computed from the category.
+	manual string
 }
 
 type CategoryStats struct {
-	Categories map[string]int
-	TotalMoved int
+	Categories  map[string]int
+	TotalMoved  int
+	Renamed     int
+	Overwritten int
+	Skipped     int
+	Duplicates  int
+	RemovedDirs int
+	UpToDate    int
+	PrunedLinks int
+	// Busy lists the files left alone because they were still being written.
+	Busy []inuse.File
+	// Archived files were packed into the bundles listed in Archives.
+	Archived      int
+	ArchivedBytes int64
+	Archives      []string
+	// Learned is the number of category changes the classifier learned.
+	Learned  int
+	LearnErr error
+
+	// files are the organized files, for the statistics.
+	files []FilePreview
 }
 
 type Model struct {
@@ -61,18 +169,64 @@ type Model struct {
 	State     state
 	Err       error
 	Path      string
+	sources   []string
 	Result    string
+	Options   Options
+	configErr error
+	learnErr  error
+
+	// Options state
+	optionCursor  int
+	optionInput   textinput.Model
+	editingOption bool
 
 	// Preview state
-	files      []FilePreview
-	totalFiles int
+	files        []FilePreview
+	totalFiles   int
+	table        table.Model
+	visible      []int
+	filterInput  textinput.Model
+	filtering    bool
+	excludedExts map[string]bool
+	ignored      int
+	busy         []inuse.File
+	dangling     []JournalMove
+	ignoreInput  textinput.Model
+	ignoring     bool
+	ignoreGlobal bool
+	eventInput   textinput.Model
+	namingEvent  string
+	// planPath is the plan file of an edit that was not accepted yet, so
+	// the next edit continues with it.
+	planPath string
+	// showStats adds the largest files and the age histogram to the preview.
+	showStats bool
 
 	// Progress state
-	progress int
-	total    int
+	progress    int
+	total       int
+	currentFile string
+	bytesMoved  int64
+	bytesTotal  int64
+	progressBar progress.Model
+	run         *organizeRun
+	aborting    bool
+	journal     *Journal
 
 	// Results
-	stats CategoryStats
+	stats     CategoryStats
+	emptyDirs []emptyDir
+
+	// Undo state
+	journals []Journal
+	cursor   int
+	revert   RevertResult
+
+	// Watch state
+	watchMode  bool
+	watch      *watchRun
+	watchLog   []WatchEvent
+	watchMoved int
 }
 
 func New() Model {
@@ -86,10 +240,76 @@ func New() Model {
 	ti.CharLimit = 256
 	ti.Width = 80
 
+	oi := textinput.New()
+	oi.CharLimit = 256
+	oi.Width = 80
+
+	fi := textinput.New()
+	fi.Prompt = "/"
+	fi.Placeholder = "Datei oder Kategorie filtern"
+	fi.CharLimit = 64
+	fi.Width = 40
+
+	ii := textinput.New()
+	ii.Placeholder = "z.B. *.tmp oder Projekte/"
+	ii.CharLimit = 256
+	ii.Width = 60
+
+	ei := textinput.New()
+	ei.Placeholder = "z.B. Sommerurlaub Gardasee"
+	ei.CharLimit = 120
+	ei.Width = 60
+
+	opts := DefaultOptions()
+	taxonomy, err := LoadTaxonomy()
+	opts.Taxonomy = taxonomy
+	classifier, learnErr := LoadClassifier()
+	opts.Classifier = classifier
+
 	return Model{
 		TextInput: ti,
 		Spinner:   s,
 		styles:    *styles.DefaulStyles(),
 		State:     stateInput,
+		Options:   opts,
+		configErr: err,
+		learnErr:  learnErr,
+
+		optionInput: oi,
+		table:       newPreviewTable(),
+		filterInput: fi,
+		ignoreInput: ii,
+		eventInput:  ei,
+		progressBar: progress.New(progress.WithDefaultGradient()),
 	}
 }
+
+// NewHistory returns a model that starts with the list of past runs instead
+// of the path input, so a run can be reverted.
+func NewHistory() Model {
+	m := New()
+	m.TextInput.Blur()
+	m.State = stateHistory
+	return m
+}
+
+// NewFlatten returns a model that moves all files below a folder up into one
+// folder and then removes the emptied subfolders.
+func NewFlatten() Model {
+	m := New()
+	m.Options.Flatten = true
+	m.Options.Recursive = true
+	m.Options.MaxDepth = 0
+	m.table.SetColumns(previewColumns(100, true))
+	return m
+}
+
+// NewWatch returns a model that asks for the folders to watch and then keeps
+// organizing new files until it is stopped.
+func NewWatch() Model {
+	m := New()
+	m.TextInput.Placeholder = fmt.Sprintf("Ordner, mehrere mit %q getrennt", string(os.PathListSeparator))
+	m.State = stateWatchInput
+	m.watchMode = true
+	return m
+}
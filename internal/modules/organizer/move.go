@@ -0,0 +1,124 @@
+package organizer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// moveFile renames srcPath to destPath. When both are on different file
+// systems the file is copied, verified and only then removed at the source.
+// If the source cannot be removed, the copy is removed again, so a failed
+// move leaves nothing behind that the journal does not know about.
+func moveFile(srcPath, destPath string) error {
+	err := os.Rename(srcPath, destPath)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyVerified(srcPath, destPath); err != nil {
+		return err
+	}
+	if err := os.Remove(srcPath); err != nil {
+		if removeErr := os.Remove(destPath); removeErr != nil {
+			return fmt.Errorf("%w (Kopie %s bleibt zurück: %v)", err, destPath, removeErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// copyFile copies src to dst, which must not exist yet, and keeps the
+// modification time and permissions of src.
+func copyFile(src, dst string) error {
+	if exists, err := pathExists(dst); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("%s existiert bereits", dst)
+	}
+	return copyVerified(src, dst)
+}
+
+// copyVerified copies src next to dst into a temporary file, flushes it to
+// disk, checks size and SHA-256 against the source and finally renames it to
+// dst. A partial copy never remains on failure.
+func copyVerified(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".ordi-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	srcHash := sha256.New()
+	if _, err = io.Copy(tmp, io.TeeReader(in, srcHash)); err != nil {
+		return fmt.Errorf("Kopieren von %s fehlgeschlagen: %w", filepath.Base(src), err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	copied, err := os.Stat(tmpPath)
+	if err != nil {
+		return err
+	}
+	if copied.Size() != info.Size() {
+		err = fmt.Errorf("Kopie von %s ist unvollständig (%d von %d Bytes)", filepath.Base(src), copied.Size(), info.Size())
+		return err
+	}
+	dstHash, err := fileHash(tmpPath)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(srcHash.Sum(nil), dstHash) {
+		err = fmt.Errorf("Prüfsumme der Kopie von %s stimmt nicht", filepath.Base(src))
+		return err
+	}
+
+	if err = os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, dst); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(dst))
+	return nil
+}
+
+// syncDir flushes a directory entry so a completed rename survives a crash.
+// Not every platform supports this, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,97 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// unknownMusic is the folder for tracks without usable tags.
+const unknownMusic = "Unbekannt"
+
+// isMusic reports whether cat is the category audio files go to (or one of
+// its subcategories).
+func (t *Taxonomy) isMusic(cat Category) bool {
+	music, ok := t.categoryForExt(".mp3")
+	return ok && topLevel(cat.Path) == topLevel(music.Path)
+}
+
+// destination returns where a file goes: tagged audio files in music mode
+// follow Artist/Album/Track - Title, photos grouped by place or event go to
+// their place or event folder unless the template places them itself,
+// everything else follows the template. In a link mode the result lies in
+// the link folder.
+func (o Options) destination(root string, tmpl *pathTemplate, v templateVars) (string, error) {
+	if o.linking() {
+		// The link tree mirrors the categories in its own folder, custom
+		// category destinations included.
+		target, err := o.linkRoot()
+		if err != nil {
+			return "", err
+		}
+		root = target
+		v.category.Root = ""
+	}
+	if o.MusicMode && v.tags != nil && o.taxonomy().isMusic(v.category) {
+		return musicPath(root, v), nil
+	}
+	if o.PlaceRadius > 0 && !tmpl.usesPlace && o.taxonomy().isPhoto(v.category) {
+		return o.placePath(root, v), nil
+	}
+	if o.EventGap > 0 && v.event != "" {
+		return o.eventPath(root, v), nil
+	}
+	return tmpl.resolve(root, v)
+}
+
+// musicPath builds <category>/<Artist>/<Album>/<Track> - <Title><ext>. Files
+// without tags keep their name in the "Unbekannt" folder.
+func musicPath(root string, v templateVars) string {
+	base := categoryDir(root, v.category)
+	tags := *v.tags
+
+	if tags.empty() {
+		return filepath.Join(base, unknownMusic, musicSegment(v.name+v.ext, v.name+v.ext))
+	}
+
+	artist := tags.Artist
+	if artist == "" {
+		artist = tags.AlbumArtist
+	}
+	title := tags.Title
+	if title == "" {
+		title = v.name
+	}
+	name := title
+	if tags.Track > 0 {
+		name = fmt.Sprintf("%02d - %s", tags.Track, title)
+	}
+
+	return filepath.Join(base,
+		musicSegment(artist, unknownMusic),
+		musicSegment(tags.Album, unknownMusic),
+		musicSegment(name, v.name)+v.ext,
+	)
+}
+
+// musicSegment makes a tag value usable as a file or folder name on all
+// platforms. fallback is used for values that end up empty.
+func musicSegment(value, fallback string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, value)
+	value = strings.TrimRight(strings.TrimSpace(value), ". ")
+	if runes := []rune(value); len(runes) > 120 {
+		value = strings.TrimSpace(string(runes[:120]))
+	}
+	if value == "" || value == "." || value == ".." {
+		return fallback
+	}
+	return value
+}
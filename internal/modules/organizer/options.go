@@ -0,0 +1,316 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Options controls how a run is planned and executed. They are chosen on the
+// options screen between the path input and the scan.
+type Options struct {
+	// Target is the folder the category tree is built in; empty organizes
+	// the source folder in place. Several sources need a target.
+	Target   string
+	Taxonomy *Taxonomy
+	// FlatCategories sorts into the top-level categories only; files of a
+	// subcategory go to its parent.
+	FlatCategories bool
+	// Classifier suggests categories for files the taxonomy cannot place.
+	Classifier *Classifier
+	Conflict   ConflictPolicy
+	Recursive  bool
+	MaxDepth   int
+	Template   string
+	// MusicMode sorts audio files by their tags instead of the template.
+	MusicMode bool
+	// Mode and LinkTarget: in a link mode the files stay where they are and
+	// the category tree is built out of links in LinkTarget.
+	Mode       OrganizeMode
+	LinkTarget string
+	// ArchiveAfter packs files older than this many days into quarterly
+	// bundles in the Archive folder instead of sorting them; 0 turns it off.
+	// Categories can set their own age.
+	ArchiveAfter  int
+	ArchiveFormat ArchiveFormat
+	// EventGap puts photos into event folders; a pause of more than this
+	// many hours starts a new event. 0 turns it off. EventNames holds the
+	// names given to events in the preview by their default name.
+	EventGap   int
+	EventNames map[string]string
+	// PlaceRadius puts photos into folders named after the nearest city;
+	// photos within this many kilometres of each other are grouped. 0 turns
+	// it off.
+	PlaceRadius int
+	// Flatten pulls all files of the tree up into the target folder
+	// instead of sorting them into categories.
+	Flatten       bool
+	FlattenNaming FlattenNaming
+
+	// readOnly marks a scan that changes nothing, such as the statistics:
+	// files still being written are planned as well instead of waiting for
+	// them to settle.
+	readOnly bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Taxonomy: defaultTaxonomy,
+		Conflict: ConflictRename,
+		MaxDepth: 3,
+		Template: DefaultTemplate,
+	}
+}
+
+// validate checks the options that can be typed in freely.
+func (o Options) validate() error {
+	if _, err := o.template(); err != nil {
+		return err
+	}
+	if o.linking() {
+		if _, err := o.linkRoot(); err != nil {
+			return err
+		}
+	}
+	if _, err := o.targetRoot("."); err != nil {
+		return err
+	}
+	return nil
+}
+
+// targetRoot returns the absolute folder files from source are sorted into.
+// In a link mode destinations are taken from the link folder instead and
+// source is returned unchanged.
+func (o Options) targetRoot(source string) (string, error) {
+	target := strings.TrimSpace(o.Target)
+	if target == "" || o.linking() {
+		return filepath.Abs(source)
+	}
+	target, err := expandHome(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(target)
+}
+
+func (o Options) template() (*pathTemplate, error) {
+	if o.Template == "" {
+		return parseTemplate(DefaultTemplate)
+	}
+	return parseTemplate(o.Template)
+}
+
+func (o Options) taxonomy() *Taxonomy {
+	t := o.Taxonomy
+	if t == nil {
+		t = defaultTaxonomy
+	}
+	if o.FlatCategories {
+		return t.flat()
+	}
+	return t
+}
+
+type optionItem struct {
+	label string
+	value func(o Options) string
+	step  func(o *Options, delta int)
+	// text is set for options that can also be typed in with "e".
+	text func(o *Options) *string
+	// placeholders marks the option whose text takes template placeholders.
+	placeholders bool
+}
+
+var targetOption = optionItem{
+	label: "Zielordner",
+	value: func(o Options) string {
+		if o.linking() {
+			return "–"
+		}
+		if strings.TrimSpace(o.Target) == "" {
+			return "Quellordner (mit e ändern)"
+		}
+		return o.Target
+	},
+	step: func(o *Options, delta int) {},
+	text: func(o *Options) *string { return &o.Target },
+}
+
+var optionItems = []optionItem{
+	targetOption,
+	{
+		label: "Bei Namenskonflikt",
+		value: func(o Options) string { return o.Conflict.String() },
+		step: func(o *Options, delta int) {
+			o.Conflict = ConflictPolicy(wrap(int(o.Conflict)+delta, int(conflictPolicyCount)))
+		},
+	},
+	{
+		label: "Unterordner einbeziehen",
+		value: func(o Options) string { return yesNo(o.Recursive) },
+		step:  func(o *Options, delta int) { o.Recursive = !o.Recursive },
+	},
+	{
+		label: "Maximale Tiefe",
+		value: func(o Options) string {
+			if !o.Recursive {
+				return "–"
+			}
+			if o.MaxDepth == 0 {
+				return "unbegrenzt"
+			}
+			return fmt.Sprintf("%d", o.MaxDepth)
+		},
+		step: func(o *Options, delta int) {
+			o.MaxDepth = wrap(o.MaxDepth+delta, maxDepthLimit+1)
+		},
+	},
+	{
+		label: "Zielpfad-Vorlage",
+		value: func(o Options) string { return o.Template },
+		step: func(o *Options, delta int) {
+			current := -1
+			for i, preset := range templatePresets {
+				if preset == o.Template {
+					current = i
+				}
+			}
+			if current < 0 {
+				o.Template = templatePresets[0]
+				return
+			}
+			o.Template = templatePresets[wrap(current+delta, len(templatePresets))]
+		},
+		text:         func(o *Options) *string { return &o.Template },
+		placeholders: true,
+	},
+	{
+		label: "Kategorien",
+		value: func(o Options) string {
+			if o.FlatCategories {
+				return "Flach (nur Hauptkategorien)"
+			}
+			return "Verschachtelt (mit Unterkategorien)"
+		},
+		step: func(o *Options, delta int) { o.FlatCategories = !o.FlatCategories },
+	},
+	{
+		label: "Musik nach Tags sortieren",
+		value: func(o Options) string { return yesNo(o.MusicMode) },
+		step:  func(o *Options, delta int) { o.MusicMode = !o.MusicMode },
+	},
+	{
+		label: "Fotos nach Ereignissen",
+		value: func(o Options) string { return eventGapLabel(o.EventGap) },
+		step: func(o *Options, delta int) {
+			// Position 0 is "off", followed by the presets.
+			current := 0
+			for i, hours := range eventGapPresets {
+				if hours == o.EventGap {
+					current = i + 1
+				}
+			}
+			next := wrap(current+delta, len(eventGapPresets)+1)
+			o.EventGap = 0
+			if next > 0 {
+				o.EventGap = eventGapPresets[next-1]
+			}
+		},
+	},
+	{
+		label: "Fotos nach Ort",
+		value: func(o Options) string { return placeRadiusLabel(o.PlaceRadius) },
+		step: func(o *Options, delta int) {
+			// Position 0 is "off", followed by the presets.
+			current := 0
+			for i, km := range placeRadiusPresets {
+				if km == o.PlaceRadius {
+					current = i + 1
+				}
+			}
+			next := wrap(current+delta, len(placeRadiusPresets)+1)
+			o.PlaceRadius = 0
+			if next > 0 {
+				o.PlaceRadius = placeRadiusPresets[next-1]
+			}
+		},
+	},
+	{
+		label: "Modus",
+		value: func(o Options) string { return o.Mode.String() },
+		step: func(o *Options, delta int) {
+			o.Mode = OrganizeMode(wrap(int(o.Mode)+delta, int(organizeModeCount)))
+		},
+	},
+	{
+		label: "Link-Zielordner",
+		value: func(o Options) string {
+			if !o.linking() {
+				return "–"
+			}
+			if o.LinkTarget == "" {
+				return "(mit e eingeben)"
+			}
+			return o.LinkTarget
+		},
+		step: func(o *Options, delta int) {},
+		text: func(o *Options) *string { return &o.LinkTarget },
+	},
+	{
+		label: "Alte Dateien archivieren",
+		value: func(o Options) string {
+			if o.ArchiveAfter == 0 {
+				return "Nein"
+			}
+			return fmt.Sprintf("älter als %d Tage", o.ArchiveAfter)
+		},
+		step: func(o *Options, delta int) {
+			// Position 0 is "off", followed by the presets.
+			current := 0
+			for i, days := range archivePresets {
+				if days == o.ArchiveAfter {
+					current = i + 1
+				}
+			}
+			next := wrap(current+delta, len(archivePresets)+1)
+			o.ArchiveAfter = 0
+			if next > 0 {
+				o.ArchiveAfter = archivePresets[next-1]
+			}
+		},
+	},
+	{
+		label: "Archivformat",
+		value: func(o Options) string {
+			if o.ArchiveAfter == 0 {
+				return "–"
+			}
+			return o.ArchiveFormat.String()
+		},
+		step: func(o *Options, delta int) {
+			o.ArchiveFormat = ArchiveFormat(wrap(int(o.ArchiveFormat)+delta, int(archiveFormatCount)))
+		},
+	},
+}
+
+// optionItems returns the options of the current action.
+func (m Model) optionItems() []optionItem {
+	if m.Options.Flatten {
+		return flattenOptionItems
+	}
+	return optionItems
+}
+
+// maxDepthLimit is the deepest selectable level; MaxDepth 0 means unlimited.
+const maxDepthLimit = 10
+
+func yesNo(b bool) string {
+	if b {
+		return "Ja"
+	}
+	return "Nein"
+}
+
+func wrap(v, n int) int {
+	return ((v % n) + n) % n
+}
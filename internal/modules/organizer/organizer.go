@@ -1,100 +1,409 @@
 package organizer
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+
+	"example/ordi/internal/inuse"
+	"example/ordi/internal/sidecar"
 )
 
-func Organize(dirPath string) (CategoryStats, error) {
-	files, err := readFiles(dirPath)
+// errAborted is returned by executePlan when the run was cancelled.
+var errAborted = errors.New("Vorgang abgebrochen")
+
+func Organize(dirPath string, opts Options) (CategoryStats, error) {
+	plan, _, _, err := buildPlan([]string{dirPath}, opts)
+	if err != nil {
+		return CategoryStats{}, err
+	}
+	root, err := opts.targetRoot(dirPath)
 	if err != nil {
 		return CategoryStats{}, err
 	}
+	stats, _, err := executePlan(context.Background(), root, plan, nil, opts, nil)
+	return stats, err
+}
 
-	stats := CategoryStats{
+// executePlan carries out a plan from buildPlan, removes the dangling links
+// of a link tree and records every change in a new journal. It stops before
+// the next file once ctx is cancelled; the returned journal can then be used
+// to roll the partial run back. report, if set, is called after every file.
+func executePlan(ctx context.Context, dirPath string, plan []FilePreview, dangling []JournalMove, opts Options, report func(OrganizeProgressMsg)) (stats CategoryStats, journal *Journal, err error) {
+	journal, err = newJournal(dirPath)
+	if err != nil {
+		return CategoryStats{}, nil, fmt.Errorf("Journal konnte nicht angelegt werden: %w", err)
+	}
+	defer func() {
+		if closeErr := journal.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	stats, err = executeInto(ctx, journal, plan, opts, report)
+	if err == nil && len(dangling) > 0 {
+		target, _ := opts.linkRoot()
+		stats.PrunedLinks, err = pruneLinks(journal, target, dangling, opts.Mode)
+	}
+	return stats, journal, err
+}
+
+// executeInto carries out plan and records the changes in an open journal.
+func executeInto(ctx context.Context, journal *Journal, plan []FilePreview, opts Options, report func(OrganizeProgressMsg)) (stats CategoryStats, err error) {
+	stats = CategoryStats{
 		Categories: make(map[string]int),
 		TotalMoved: 0,
 	}
+	claimed := make(map[string]bool)
+	checker := inuse.NewChecker()
+
+	progress := OrganizeProgressMsg{Total: len(plan)}
+	for _, file := range plan {
+		progress.BytesTotal += file.Size
+	}
+
+	// Stale files are packed after everything else has been sorted.
+	// Sidecars are moved after their primary file, and only if it was.
+	var moves, companions, stale []FilePreview
+	for _, file := range plan {
+		switch {
+		case file.Action == actionArchive:
+			if reason, ok := fileInUse(checker, file); ok {
+				stats.Busy = append(stats.Busy, inuse.File{Path: file.Path, Reason: reason})
+				continue
+			}
+			stale = append(stale, file)
+		case file.CompanionOf != "":
+			companions = append(companions, file)
+		default:
+			moves = append(moves, file)
+		}
+	}
+	moves = append(moves, companions...)
+	// left are the files that stay where they are, placed the actual
+	// destinations of the files moved so far.
+	left := make(map[string]bool)
+	for _, file := range stats.Busy {
+		left[file.Path] = true
+	}
+	placed := make(map[string]string)
 
-	for _, file := range files {
-		if file.IsDir() {
+	for i, file := range moves {
+		if ctx.Err() != nil {
+			return stats, errAborted
+		}
+		if left[file.CompanionOf] {
+			left[file.Path] = true
 			continue
 		}
-		category := getCategory(file.Name())
-		err := createDir(dirPath, category.Name)
+		if report != nil {
+			progress.Current = i
+			progress.File = file.Rel
+			report(progress)
+		}
+
+		srcPath := file.Path
+		destPath := file.Dest
+		action := file.Action
+
+		info, err := os.Stat(srcPath)
 		if err != nil {
 			return stats, err
 		}
-		srcPath := filepath.Join(dirPath, file.Name())
-		destDir := filepath.Join(dirPath, category.Name)
 
-		err = moveFile(srcPath, destDir)
+		// The destination may have appeared since the preview was built.
+		if action == actionMove || action == actionRename {
+			if dest, ok := placed[file.CompanionOf]; ok {
+				destPath = sidecar.Dest(file.CompanionOf, srcPath, dest)
+			}
+			destPath, action, err = resolveTarget(srcPath, destPath, opts, claimed)
+			if err != nil {
+				return stats, err
+			}
+			if file.Action == actionRename && action == actionMove {
+				action = actionRename
+			}
+		}
+
+		// The entry a link replaces must still be one of an earlier run.
+		var replaced JournalMove
+		if opts.linking() && action == actionOverwrite {
+			link, ok, err := replaceableLink(destPath)
+			if err != nil {
+				return stats, err
+			}
+			if !ok {
+				action = actionSkip
+			}
+			replaced = link
+		}
+
+		switch action {
+		case actionSkip:
+			stats.Skipped++
+			left[srcPath] = true
+			continue
+		case actionLinked:
+			stats.UpToDate++
+			continue
+		}
+
+		// Links leave the original untouched, so only moves and deletes
+		// have to wait for a file to be finished.
+		if !opts.linking() {
+			if reason, ok := fileInUse(checker, file); ok {
+				stats.Busy = append(stats.Busy, inuse.File{Path: srcPath, Reason: reason})
+				left[srcPath] = true
+				continue
+			}
+		}
+		placed[srcPath] = destPath
+
+		switch action {
+		case actionDropIdentical:
+			destInfo, err := os.Stat(destPath)
+			if err != nil {
+				return stats, err
+			}
+			if err := os.Remove(srcPath); err != nil {
+				return stats, err
+			}
+			if err := journal.recordDrop(srcPath, destPath, info, destInfo); err != nil {
+				return stats, err
+			}
+			stats.Duplicates++
+			continue
+		}
+
+		created, err := createDir(filepath.Dir(destPath))
 		if err != nil {
 			return stats, err
 		}
+		for _, dir := range created {
+			if err := journal.recordDir(dir); err != nil {
+				return stats, err
+			}
+		}
+
+		if opts.linking() {
+			if action == actionOverwrite {
+				if err := os.Remove(destPath); err != nil {
+					return stats, err
+				}
+				if err := journal.recordPrune(replaced); err != nil {
+					return stats, err
+				}
+			}
+			if err := createLink(srcPath, destPath, opts.Mode); err != nil {
+				return stats, err
+			}
+			claimed[destPath] = true
+			if err := journal.recordLink(srcPath, destPath, info); err != nil {
+				return stats, err
+			}
+		} else {
+			if err := moveFile(srcPath, destPath); err != nil {
+				return stats, err
+			}
+			claimed[destPath] = true
+			// A copy to another file system keeps the modification time
+			// only as precisely as that file system stores it, so the
+			// journal describes the file as it arrived.
+			moved, err := os.Stat(destPath)
+			if err != nil {
+				moved = info
+			}
+			if err := journal.recordMove(srcPath, destPath, moved, action == actionOverwrite); err != nil {
+				return stats, err
+			}
+		}
 
-		stats.Categories[category.Name]++
+		switch action {
+		case actionRename:
+			stats.Renamed++
+		case actionOverwrite:
+			stats.Overwritten++
+		}
+		stats.Categories[file.Category]++
 		stats.TotalMoved++
+		file.Dest = destPath
+		stats.files = append(stats.files, file)
+		progress.BytesMoved += info.Size()
+	}
+
+	if len(stale) > 0 {
+		progress.Current = len(moves)
+		err := archiveFiles(ctx, journal, stale, &stats, func(file FilePreview) {
+			if report != nil {
+				progress.File = file.Rel
+				report(progress)
+			}
+			progress.Current++
+			progress.BytesMoved += file.Size
+		})
+		if err != nil {
+			return stats, err
+		}
 	}
 
+	if report != nil {
+		progress.Current = len(plan)
+		progress.File = ""
+		report(progress)
+	}
 	return stats, nil
 }
 
+// fileInUse checks right before a file is touched whether it is being
+// written or has changed since it was planned.
+func fileInUse(checker *inuse.Checker, file FilePreview) (string, bool) {
+	if reason, ok := checker.Busy(file.Path); ok {
+		return reason, true
+	}
+	if inuse.Changed(file.Path, file.vars.size, file.vars.modTime) {
+		return "wurde seit dem Scan verändert", true
+	}
+	return "", false
+}
+
+// Category is a destination bucket. Path is the folder the files go to,
+// relative to the organized directory ("Dokumente/Tabellen"); Root is set
+// instead when the category has an absolute destination of its own.
 type Category struct {
-	Name string
-	Icon string
+	Name          string     `json:"name"`
+	Icon          string     `json:"icon,omitempty"`
+	Extensions    []string   `json:"extensions,omitempty"`
+	Patterns      []string   `json:"patterns,omitempty"`
+	Destination   string     `json:"destination,omitempty"`
+	Fallback      bool       `json:"fallback,omitempty"`
+	Subcategories []Category `json:"subcategories,omitempty"`
+	// ArchiveAfterDays overrides the age at which files of this category
+	// are archived; -1 keeps them from being archived at all.
+	ArchiveAfterDays int `json:"archive_after_days,omitempty"`
+
+	Path     string           `json:"-"`
+	Root     string           `json:"-"`
+	patterns []*regexp.Regexp `json:"-"`
 }
 
-var categories = map[string]Category{
-	"images": {Name: "Bilder", Icon: "📷"},
-	"videos": {Name: "Videos", Icon: "🎬"},
-	"music":  {Name: "Musik", Icon: "🎵"},
-	"docs":   {Name: "Dokumente", Icon: "📄"},
-	"archives": {Name: "Archive", Icon: "📦"},
-	"other":  {Name: "Sonstiges", Icon: "📁"},
+func (c Category) matches(fileName string, withPatterns bool) bool {
+	if withPatterns {
+		for _, re := range c.patterns {
+			if re.MatchString(fileName) {
+				return true
+			}
+		}
+	}
+	// Suffix matching handles upper case names and compound extensions
+	// such as ".tar.gz".
+	lower := strings.ToLower(fileName)
+	for _, e := range c.Extensions {
+		e = strings.ToLower(e)
+		if len(lower) > len(e) && strings.HasSuffix(lower, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the most specific category below c that fits fileName.
+func (c Category) match(fileName string, withPatterns bool) (Category, bool) {
+	for _, sub := range c.Subcategories {
+		if found, ok := sub.match(fileName, withPatterns); ok {
+			return found, true
+		}
+	}
+	if c.matches(fileName, withPatterns) {
+		return c, true
+	}
+	return Category{}, false
 }
 
-func getCategory(fileName string) Category {
-	ext := filepath.Ext(fileName)
-	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff", ".webp":
-		return categories["images"]
-	case ".mp4", ".mkv", ".avi", ".mov", ".wmv":
-		return categories["videos"]
-	case ".mp3", ".wav", ".flac", ".aac", ".ogg":
-		return categories["music"]
-	case ".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".txt":
-		return categories["docs"]
-	case ".zip", ".rar", ".tar", ".gz", ".7z":
-		return categories["archives"]
-	default:
-		return categories["other"]
+func (t *Taxonomy) getCategory(fileName string) Category {
+	for _, cat := range t.Categories {
+		if found, ok := cat.match(fileName, true); ok {
+			return found
+		}
 	}
+	return t.fallback
 }
 
-func getCategoryIcon(categoryName string) string {
-	for _, cat := range categories {
-		if cat.Name == categoryName {
+// extensionCategory returns the category of fileName by its extension alone,
+// ignoring filename patterns.
+func (t *Taxonomy) extensionCategory(fileName string) Category {
+	for _, cat := range t.Categories {
+		if found, ok := cat.match(fileName, false); ok {
+			return found
+		}
+	}
+	return t.fallback
+}
+
+// categoryForExt returns the category an extension belongs to, ignoring
+// filename patterns.
+func (t *Taxonomy) categoryForExt(ext string) (Category, bool) {
+	for _, cat := range t.Categories {
+		if found, ok := cat.match("file"+ext, false); ok {
+			return found, true
+		}
+	}
+	return t.fallback, false
+}
+
+func (t *Taxonomy) getCategoryIcon(categoryPath string) string {
+	for _, cat := range t.flatten() {
+		if cat.Path == categoryPath {
 			return cat.Icon
 		}
 	}
 	return "📁"
 }
 
-func readFiles(dirPath string) ([]os.DirEntry, error) {
-	files, err := os.ReadDir(dirPath)
-	if err != nil {
-		return nil, err
+// categoryOrder lists all category paths in definition order, parents before
+// their subcategories.
+func (t *Taxonomy) categoryOrder() []string {
+	var order []string
+	for _, cat := range t.flatten() {
+		order = append(order, cat.Path)
 	}
-	return files, nil
+	return order
 }
 
-func createDir(dirPath, category string) error {
-	categoryPath := filepath.Join(dirPath, category)
-	return os.MkdirAll(categoryPath, os.ModePerm)
+// categoryDir returns the absolute folder for files of cat when organizing root.
+func categoryDir(root string, cat Category) string {
+	if cat.Root != "" {
+		return cat.Root
+	}
+	return filepath.Join(root, filepath.FromSlash(cat.Path))
 }
 
-func moveFile(filePath, destDir string) error {
-	destPath := filepath.Join(destDir, filepath.Base(filePath))
-	return os.Rename(filePath, destPath)
+// createDir creates the destination folder and returns the directories that
+// did not exist before, outermost first.
+func createDir(dirPath string) ([]string, error) {
+	categoryPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for p := categoryPath; ; p = filepath.Dir(p) {
+		if _, err := os.Stat(p); err == nil {
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		missing = append([]string{p}, missing...)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(categoryPath, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return missing, nil
 }
@@ -0,0 +1,94 @@
+package organizer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecutePlanAbort(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	names := []string{"a.jpg", "b.pdf", "c.mp3", "d.txt", "e.zip"}
+	for _, name := range names {
+		writeFile(t, filepath.Join(dir, name), name)
+	}
+	opts := DefaultOptions()
+	plan, _, _, err := buildPlan([]string{dir}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancel while the third file is reported: it is still moved, the run
+	// stops before the fourth.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := 0
+	_, journal, err := executePlan(ctx, dir, plan, nil, opts, func(p OrganizeProgressMsg) {
+		reports++
+		if p.Current == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, errAborted) {
+		t.Fatalf("executePlan = %v, erwartet %v", err, errAborted)
+	}
+	if reports != 3 {
+		t.Errorf("%d Fortschrittsmeldungen, erwartet 3", reports)
+	}
+	if journal == nil || len(journal.Moves) != 3 {
+		t.Fatalf("Journal nach dem Abbruch: %+v, erwartet 3 Verschiebungen", journal)
+	}
+
+	// The journal holds exactly the files that were moved.
+	moved := make(map[string]bool)
+	for _, move := range journal.Moves {
+		moved[move.Src] = true
+		if _, err := os.Stat(move.Dst); err != nil {
+			t.Errorf("%s fehlt am Ziel: %v", move.Dst, err)
+		}
+		if _, err := os.Stat(move.Src); !os.IsNotExist(err) {
+			t.Errorf("%s liegt noch an der Quelle", move.Src)
+		}
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); !moved[path] && err != nil {
+			t.Errorf("%s wurde ohne Journaleintrag verschoben", name)
+		}
+	}
+
+	read, err := readJournal(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Moves) != len(journal.Moves) {
+		t.Errorf("%d Einträge auf der Platte, erwartet %d", len(read.Moves), len(journal.Moves))
+	}
+
+	result, err := Revert(&read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Restored != 3 || len(result.Problems) > 0 {
+		t.Errorf("Revert = %+v", result)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+	if len(left) != len(names) {
+		t.Errorf("nach dem Zurücksetzen liegen %v im Ordner, erwartet %v", left, names)
+	}
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s nicht zurückgeholt: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package organizer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example/ordi/internal/ignore"
+	"example/ordi/internal/inuse"
+	"example/ordi/internal/sidecar"
+)
+
+// sourceFile is a regular file below a source folder (Root) that is a
+// candidate for organizing. Depth is 0 for files directly in the root.
+type sourceFile struct {
+	Root  string
+	Path  string
+	Rel   string
+	Depth int
+	Info  os.FileInfo
+}
+
+// collectFiles lists the files to organize. Without opts.Recursive only the
+// top level is read; otherwise subfolders are walked down to opts.MaxDepth
+// (0 = unlimited), skipping the target folder with its categories and
+// archive bundles. Entries excluded by ignore rules are left out and counted
+// in ignored.
+func collectFiles(root string, opts Options) (files []sourceFile, ignored int, err error) {
+	matcher, err := ignore.New(root)
+	if err != nil {
+		return nil, 0, err
+	}
+	target, err := opts.targetRoot(root)
+	if err != nil {
+		return nil, 0, err
+	}
+	linkRoot := ""
+	if opts.linking() {
+		if linkRoot, err = opts.linkRoot(); err != nil {
+			return nil, 0, err
+		}
+		if linkRoot == root {
+			return nil, 0, fmt.Errorf("Der Link-Zielordner muss sich vom Quellordner unterscheiden.")
+		}
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Unreadable subfolders are left out instead of aborting the scan.
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		depth := 0
+		if rel != "." {
+			depth = strings.Count(rel, string(filepath.Separator))
+		}
+
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			if !opts.Recursive || (opts.MaxDepth > 0 && depth+1 > opts.MaxDepth) {
+				return filepath.SkipDir
+			}
+			if path == target || path == linkRoot {
+				return filepath.SkipDir
+			}
+			// When flattening, the whole tree is pulled up, category
+			// folders included.
+			if !opts.Flatten && (opts.taxonomy().isCategoryDir(target, path) || path == filepath.Join(target, archiveDirName)) {
+				return filepath.SkipDir
+			}
+			if matcher.Match(path, true) {
+				ignored++
+				return filepath.SkipDir
+			}
+			if err := matcher.ReadDir(path); err != nil {
+				return err
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if d.Name() == ignore.FileName || isJunkFile(d.Name()) {
+			return nil
+		}
+		if matcher.Match(path, false) {
+			ignored++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, sourceFile{Root: root, Path: path, Rel: rel, Depth: depth, Info: info})
+		return nil
+	})
+
+	return files, ignored, err
+}
+
+// buildPlan decides for every file of the source folders where it goes and
+// how name conflicts are resolved. The preview shows exactly this plan;
+// ignored is the number of entries excluded by ignore rules, busy lists the
+// files left out because they are still being written.
+func buildPlan(sources []string, opts Options) (plan []FilePreview, ignored int, busy []inuse.File, err error) {
+	if len(sources) > 1 && strings.TrimSpace(opts.Target) == "" && !opts.linking() {
+		return nil, 0, nil, fmt.Errorf("Für mehrere Quellordner bitte einen Zielordner angeben.")
+	}
+	if opts.Flatten {
+		return buildFlattenPlan(sources, opts)
+	}
+
+	var files []sourceFile
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		root, err := filepath.Abs(source)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		found, n, err := collectFiles(root, opts)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		files = append(files, found...)
+		ignored += n
+	}
+
+	tmpl, err := opts.template()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+	}
+	if !opts.readOnly {
+		busy = inuse.Check(paths)
+	}
+	skip := inuse.Set(busy)
+
+	claimed := make(map[string]bool)
+	owners := make(map[string]destOwner)
+
+	for _, file := range files {
+		if skip[file.Path] {
+			continue
+		}
+		entry, err := planFile(file, tmpl, opts, claimed, len(plan)+1)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if err := settleClash(&entry, owners, claimed); err != nil {
+			return nil, 0, nil, err
+		}
+		plan = append(plan, entry)
+	}
+
+	companions := linkCompanions(plan)
+	if companions || opts.EventGap > 0 || opts.PlaceRadius > 0 {
+		// Events and places are only known once all photos are planned,
+		// sidecars once their primary file is.
+		if err := replan(plan, opts); err != nil {
+			return nil, 0, nil, err
+		}
+	}
+	return plan, ignored, busy, nil
+}
+
+// planFile decides the destination of a single file. Destinations handed
+// out are added to claimed so later files of the same plan avoid them.
+func planFile(file sourceFile, tmpl *pathTemplate, opts Options, claimed map[string]bool, seq int) (FilePreview, error) {
+	root, err := opts.targetRoot(file.Root)
+	if err != nil {
+		return FilePreview{}, err
+	}
+	name := file.Info.Name()
+	category, detected, mismatch := opts.taxonomy().classify(file.Path)
+	var confidence float64
+	if category.Path == opts.taxonomy().fallback.Path {
+		if learned, p, ok := opts.Classifier.suggest(opts.taxonomy(), file.Path, file.Info.Size()); ok {
+			category, confidence = learned, p
+		}
+	}
+
+	base, ext := splitName(name)
+	vars := templateVars{
+		category: category,
+		name:     base,
+		ext:      ext,
+		modTime:  file.Info.ModTime(),
+		size:     file.Info.Size(),
+		seq:      seq,
+	}
+	if tmpl.usesExif || ((opts.EventGap > 0 || opts.PlaceRadius > 0) && opts.taxonomy().isPhoto(category)) {
+		if exif, err := readExif(file.Path); err == nil {
+			vars.taken = exif.Taken
+			if exif.GPS != nil {
+				vars.gps, vars.place = exif.GPS, placeName(*exif.GPS)
+			}
+		}
+	}
+	if opts.MusicMode && opts.taxonomy().isMusic(category) {
+		tags, _ := readTags(file.Path)
+		vars.tags = &tags
+	}
+
+	target, dest, action, err := planTarget(root, file.Path, tmpl, vars, opts, claimed)
+	if err != nil {
+		return FilePreview{}, err
+	}
+
+	return FilePreview{
+		Name:      name,
+		Source:    file.Root,
+		Path:      file.Path,
+		Rel:       file.Rel,
+		Depth:     file.Depth,
+		Category:  category.Path,
+		Icon:      category.Icon,
+		Size:      file.Info.Size(),
+		Dest:      dest,
+		Action:    action,
+		Detected:  detected.Label,
+		Mismatch:  mismatch,
+		Suggested: confidence,
+		vars:      vars,
+		target:    target,
+		auto:      category.Path,
+	}, nil
+}
+
+// planTarget returns where a file should go, where it actually goes after
+// name conflicts are settled and what happens to it. Files old enough to be
+// archived go to their bundle, which has no name conflicts of its own.
+func planTarget(root, src string, tmpl *pathTemplate, v templateVars, opts Options, claimed map[string]bool) (target, dest string, action conflictAction, err error) {
+	if bundle, ok := opts.archiveBundle(root, v); ok {
+		return bundle, bundle, actionArchive, nil
+	}
+	target, err = opts.destination(root, tmpl, v)
+	if err != nil {
+		return "", "", actionMove, err
+	}
+	dest, action, err = resolveTarget(src, target, opts, claimed)
+	return target, dest, action, err
+}
+
+// resolveTarget settles name conflicts for one planned file and claims the
+// destination. In a link mode an existing link to the file is left as it is,
+// the original is never removed in favour of an identical file and only links
+// of earlier runs are overwritten; other files are renamed around.
+func resolveTarget(src, target string, opts Options, claimed map[string]bool) (string, conflictAction, error) {
+	if opts.linking() && isLinkTo(src, target) {
+		claimed[target] = true
+		return target, actionLinked, nil
+	}
+
+	dest, action, err := resolveConflict(src, target, opts.Conflict, claimed)
+	if err != nil {
+		return "", actionMove, err
+	}
+	if opts.linking() && action == actionDropIdentical {
+		action = actionSkip
+	}
+	if opts.linking() && action == actionOverwrite {
+		if _, ok, err := replaceableLink(dest); err != nil {
+			return "", actionMove, err
+		} else if !ok {
+			if dest, err = uniqueName(target, claimed); err != nil {
+				return "", actionMove, err
+			}
+			action = actionRename
+		}
+	}
+	if action != actionSkip && action != actionDropIdentical {
+		claimed[dest] = true
+	}
+	return dest, action, nil
+}
+
+// replan recomputes destinations and name conflicts after the user changed
+// categories or excluded files in the preview. Excluded files do not claim
+// their destination, so other files may take it.
+func replan(plan []FilePreview, opts Options) error {
+	if opts.Flatten {
+		return flattenTargets(plan, opts)
+	}
+	tmpl, err := opts.template()
+	if err != nil {
+		return err
+	}
+	assignEvents(plan, opts)
+	assignPlaces(plan, opts)
+
+	claimed := make(map[string]bool)
+	owners := make(map[string]destOwner)
+	for i := range plan {
+		entry := &plan[i]
+		if entry.excluded() || entry.CompanionOf != "" {
+			continue
+		}
+		root, err := opts.targetRoot(entry.Source)
+		if err != nil {
+			return err
+		}
+		var target, dest string
+		var action conflictAction
+		if entry.manual != "" {
+			target = entry.manual
+			dest, action, err = resolveTarget(entry.Path, target, opts, claimed)
+		} else {
+			target, dest, action, err = planTarget(root, entry.Path, tmpl, entry.vars, opts, claimed)
+		}
+		if err != nil {
+			return err
+		}
+		entry.target, entry.Dest, entry.Action = target, dest, action
+		if err := settleClash(entry, owners, claimed); err != nil {
+			return err
+		}
+	}
+	return followPrimaries(plan, opts, claimed)
+}
+
+// linkCompanions marks the sidecar files whose primary file is part of the
+// plan and reports whether there are any.
+func linkCompanions(plan []FilePreview) bool {
+	paths := make([]string, len(plan))
+	index := make(map[string]int)
+	for i, file := range plan {
+		paths[i] = file.Path
+		index[file.Path] = i
+	}
+	_, companions := sidecar.Split(paths)
+	for primary, files := range companions {
+		for _, file := range files {
+			plan[index[file]].CompanionOf = primary
+		}
+	}
+	return len(companions) > 0
+}
+
+// followPrimaries sends every sidecar file where its primary file goes: next
+// to it under its new name, or into the same archive. A sidecar is excluded
+// with its primary file and stays where it is when that file does.
+func followPrimaries(plan []FilePreview, opts Options, claimed map[string]bool) error {
+	index := make(map[string]int)
+	for i, file := range plan {
+		index[file.Path] = i
+	}
+	for i := range plan {
+		entry := &plan[i]
+		if entry.CompanionOf == "" {
+			continue
+		}
+		primary := plan[index[entry.CompanionOf]]
+		entry.Excluded = primary.excluded()
+		entry.Category, entry.Icon, entry.vars.category = primary.Category, primary.Icon, primary.vars.category
+		entry.ClashWith = ""
+		if entry.excluded() {
+			continue
+		}
+
+		switch primary.Action {
+		case actionArchive:
+			entry.target, entry.Dest, entry.Action = primary.Dest, primary.Dest, actionArchive
+		case actionSkip:
+			entry.target = sidecar.Dest(primary.Path, entry.Path, primary.target)
+			entry.Dest, entry.Action = entry.target, actionSkip
+		default:
+			target := sidecar.Dest(primary.Path, entry.Path, primary.Dest)
+			dest, action, err := resolveTarget(entry.Path, target, opts, claimed)
+			if err != nil {
+				return err
+			}
+			entry.target, entry.Dest, entry.Action = target, dest, action
+		}
+	}
+	return nil
+}
+
+// destOwner is the file of a plan that first wanted a destination.
+type destOwner struct {
+	source string
+	path   string
+}
+
+// settleClash flags a file that wants the same name as a file from another
+// source folder. Such a file was already given way to by the conflict
+// policy, which renames or skips it since the name is claimed by the
+// earlier file; the flag lets the preview point it out. Once the user chose
+// to keep both, it is renamed whatever the policy.
+func settleClash(entry *FilePreview, owners map[string]destOwner, claimed map[string]bool) error {
+	entry.ClashWith = ""
+	if entry.Action == actionArchive || entry.Action == actionLinked {
+		return nil
+	}
+	owner, ok := owners[entry.target]
+	if !ok {
+		owners[entry.target] = destOwner{source: entry.Source, path: entry.Path}
+		return nil
+	}
+	if owner.source == entry.Source {
+		return nil
+	}
+
+	entry.ClashWith = owner.path
+	if entry.KeepBoth && entry.Action != actionRename {
+		dest, err := uniqueName(entry.target, claimed)
+		if err != nil {
+			return err
+		}
+		claimed[dest] = true
+		entry.Dest, entry.Action = dest, actionRename
+	}
+	return nil
+}
+
+// setCategory assigns the file to cat, overriding the automatic choice.
+func (f *FilePreview) setCategory(cat Category) {
+	f.Category = cat.Path
+	f.Icon = cat.Icon
+	f.vars.category = cat
+	f.Override = true
+	f.Suggested = 0
+	f.manual = ""
+}
+
+func (f FilePreview) excluded() bool {
+	return f.Excluded || f.ExtExcluded
+}
+
+// extKey is the extension used to exclude all files of a type at once.
+func (f FilePreview) extKey() string {
+	return strings.ToLower(f.vars.ext)
+}
+
+// approvedPlan returns the entries of plan that were not excluded.
+func approvedPlan(plan []FilePreview) []FilePreview {
+	var approved []FilePreview
+	for _, entry := range plan {
+		if !entry.excluded() {
+			approved = append(approved, entry)
+		}
+	}
+	return approved
+}
@@ -0,0 +1,194 @@
+package organizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCollectFiles(t *testing.T) {
+	tree := []string{
+		"a.jpg",
+		"Thumbs.db",
+		"alt.log",
+		"sub/b.pdf",
+		"sub/tief/c.mp3",
+		"sub/tief/tiefer/d.txt",
+		"sub/ignoriert/e.txt",
+		"Bilder/schon.jpg",
+		"Dokumente/Texte/schon.txt",
+		"Ordi-Archiv/2023-Q4.tar.zst",
+		"ziel/Bilder/da.jpg",
+	}
+	tests := []struct {
+		name      string
+		recursive bool
+		maxDepth  int
+		target    string
+		want      []string
+		ignored   int
+	}{
+		{
+			name:    "nur oberste Ebene",
+			want:    []string{"a.jpg"},
+			ignored: 1,
+		},
+		{
+			name:      "Tiefe 1",
+			recursive: true,
+			maxDepth:  1,
+			want:      []string{"a.jpg", "sub/b.pdf"},
+			ignored:   1,
+		},
+		{
+			name:      "Tiefe 2",
+			recursive: true,
+			maxDepth:  2,
+			want:      []string{"a.jpg", "sub/b.pdf", "sub/tief/c.mp3", "ziel/Bilder/da.jpg"},
+			ignored:   2,
+		},
+		{
+			name:      "unbegrenzt",
+			recursive: true,
+			want:      []string{"a.jpg", "sub/b.pdf", "sub/tief/c.mp3", "sub/tief/tiefer/d.txt", "ziel/Bilder/da.jpg"},
+			ignored:   2,
+		},
+		{
+			name:      "eigener Zielordner",
+			recursive: true,
+			target:    "ziel",
+			want: []string{"Bilder/schon.jpg", "Dokumente/Texte/schon.txt", "Ordi-Archiv/2023-Q4.tar.zst",
+				"a.jpg", "sub/b.pdf", "sub/tief/c.mp3", "sub/tief/tiefer/d.txt"},
+			ignored: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			for _, name := range tree {
+				writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), name)
+			}
+			writeFile(t, filepath.Join(dir, ".ordiignore"), "*.log\nsub/ignoriert/\n")
+
+			opts := DefaultOptions()
+			opts.Recursive = tt.recursive
+			opts.MaxDepth = tt.maxDepth
+			if tt.target != "" {
+				opts.Target = filepath.Join(dir, tt.target)
+			}
+			files, ignored, err := collectFiles(dir, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, file := range files {
+				got = append(got, filepath.ToSlash(file.Rel))
+				if file.Root != dir || file.Path != filepath.Join(dir, file.Rel) {
+					t.Errorf("%s: Wurzel %s, Pfad %s", file.Rel, file.Root, file.Path)
+				}
+				if depth := strings.Count(filepath.ToSlash(file.Rel), "/"); file.Depth != depth {
+					t.Errorf("%s: Tiefe %d, erwartet %d", file.Rel, file.Depth, depth)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectFiles = %v, erwartet %v", got, tt.want)
+			}
+			if ignored != tt.ignored {
+				t.Errorf("%d ignoriert, erwartet %d", ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestMultipleSourcesClash(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   ConflictPolicy
+		keepBoth bool
+		action   conflictAction
+		dest     string
+	}{
+		{name: "umbenennen", policy: ConflictRename, action: actionRename, dest: "bild (2)"},
+		{name: "überspringen", policy: ConflictSkip, action: actionSkip, dest: "bild"},
+		{name: "überspringen, beide behalten", policy: ConflictSkip, keepBoth: true, action: actionRename, dest: "bild (2)"},
+		// A file of the same run is never overwritten.
+		{name: "überschreiben", policy: ConflictOverwrite, action: actionRename, dest: "bild (2)"},
+		{name: "identische verwerfen", policy: ConflictKeepIfIdentical, action: actionRename, dest: "bild (2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			first, second := filepath.Join(dir, "usb"), filepath.Join(dir, "desktop")
+			writeFile(t, filepath.Join(first, "bild.jpg"), "gleich")
+			writeFile(t, filepath.Join(second, "bild.jpg"), "gleich")
+			writeFile(t, filepath.Join(second, "bild.xmp"), "xmp")
+
+			opts := DefaultOptions()
+			opts.Target = filepath.Join(dir, "ziel")
+			opts.Conflict = tt.policy
+			plan, _, _, err := buildPlan([]string{first, second}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.keepBoth {
+				for i := range plan {
+					plan[i].KeepBoth = plan[i].ClashWith != ""
+				}
+				if err := replan(plan, opts); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files := make(map[string]FilePreview)
+			for _, file := range plan {
+				files[file.Path] = file
+			}
+			bilder := filepath.Join(dir, "ziel", "Bilder")
+			owner := files[filepath.Join(first, "bild.jpg")]
+			if owner.Dest != filepath.Join(bilder, "bild.jpg") || owner.Action != actionMove || owner.ClashWith != "" {
+				t.Errorf("erste Quelle: Ziel %s (%v), Konflikt mit %q", owner.Dest, owner.Action, owner.ClashWith)
+			}
+			clash := files[filepath.Join(second, "bild.jpg")]
+			if clash.ClashWith != owner.Path {
+				t.Errorf("Konflikt mit %q, erwartet %s", clash.ClashWith, owner.Path)
+			}
+			if want := filepath.Join(bilder, tt.dest+".jpg"); clash.Dest != want || clash.Action != tt.action {
+				t.Errorf("zweite Quelle: Ziel %s (%v), erwartet %s (%v)", clash.Dest, clash.Action, want, tt.action)
+			}
+			// The sidecar follows its primary file.
+			xmp := files[filepath.Join(second, "bild.xmp")]
+			if want := filepath.Join(bilder, tt.dest+".xmp"); xmp.Dest != want {
+				t.Errorf("Begleitdatei: Ziel %s, erwartet %s", xmp.Dest, want)
+			}
+			if skipped := tt.action == actionSkip; (xmp.Action == actionSkip) != skipped {
+				t.Errorf("Begleitdatei: %v", xmp.Action)
+			}
+			if countClashes(plan) != 1 {
+				t.Errorf("%d Konflikte zwischen Quellen, erwartet 1", countClashes(plan))
+			}
+
+			if _, _, err := executePlan(context.Background(), opts.Target, approvedPlan(plan), nil, opts, nil); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(bilder, "bild.jpg"))
+			if err != nil || string(data) != "gleich" {
+				t.Errorf("Datei der ersten Quelle: %q, %v", data, err)
+			}
+			moved := clash.Dest
+			if tt.action == actionSkip {
+				moved = clash.Path
+			}
+			if _, err := os.Stat(moved); err != nil {
+				t.Errorf("Datei der zweiten Quelle nicht in %s: %v", moved, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package organizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxPlanErrors is how many problems of an edited plan are reported at once.
+const maxPlanErrors = 3
+
+// planRoot returns the folder destinations of files from source are written
+// relative to in the plan file.
+func (o Options) planRoot(source string) (string, error) {
+	if o.linking() {
+		return o.linkRoot()
+	}
+	return o.targetRoot(source)
+}
+
+// editablePlan reports whether a file appears in the plan file. Files of an
+// excluded extension and files that go into an archive are left out and kept
+// as they are; sidecars follow their primary file.
+func editablePlan(file FilePreview) bool {
+	return !file.ExtExcluded && file.Action != actionArchive && file.CompanionOf == ""
+}
+
+// writePlan saves the plan as a tab separated file for an editor: source,
+// category and destination, one file per line. Excluded files are commented
+// out.
+func writePlan(plan []FilePreview, opts Options) (string, error) {
+	f, err := os.CreateTemp("", "ordi-plan-*.tsv")
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, cat := range opts.taxonomy().flatten() {
+		names = append(names, cat.Path)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# Ordi-Plan: eine Zeile pro Datei, Spalten durch Tabulatoren getrennt:")
+	fmt.Fprintln(w, "# Quelle	Kategorie	Ziel")
+	fmt.Fprintln(w, "#")
+	fmt.Fprintln(w, "# Kategorie oder Ziel ändern, um eine Datei anders einzusortieren; ein leeres Ziel")
+	fmt.Fprintln(w, "# wird aus der Kategorie berechnet. Das Ziel ist relativ zum Zielordner, ein Ziel")
+	fmt.Fprintln(w, "# mit / am Ende ist ein Ordner. Zeile löschen oder mit # auskommentieren = Datei ausschließen.")
+	if opts.Flatten {
+		fmt.Fprintln(w, "# Die zweite Spalte zeigt nur den Ordner, aus dem eine Datei stammt.")
+	} else {
+		fmt.Fprintf(w, "# Kategorien: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintln(w)
+	for _, file := range plan {
+		if !editablePlan(file) {
+			continue
+		}
+		line := strings.Join([]string{file.Path, file.Category, opts.planRel(file.Source, file.Dest)}, "\t")
+		if file.Excluded {
+			line = "# " + line
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// planRel is a destination of a file from source as written to the plan
+// file: relative to the target folder if it lies inside, else absolute.
+func (o Options) planRel(source, path string) string {
+	root, err := o.planRoot(source)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// editPlan writes the plan, or reopens the file of an edit that was not
+// accepted, and opens it in the user's editor.
+func editPlan(path string, plan []FilePreview, opts Options) tea.Cmd {
+	if path == "" {
+		var err error
+		if path, err = writePlan(plan, opts); err != nil {
+			return func() tea.Msg { return PlanEditedMsg{Err: err} }
+		}
+	}
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		return PlanEditedMsg{Path: path, Err: err}
+	})
+}
+
+// editorCommand opens path in $VISUAL or $EDITOR, falling back to vi or, on
+// Windows, Notepad.
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			args = []string{"notepad"}
+		}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// applyPlan reads an edited plan and returns a copy of plan with its changes:
+// other categories, destinations typed by hand and excluded files. Unknown
+// files or categories, destinations outside the target folder and
+// destinations used twice are rejected as a whole.
+func applyPlan(plan []FilePreview, text string, opts Options) ([]FilePreview, error) {
+	edited := append([]FilePreview(nil), plan...)
+	index := make(map[string]int)
+	for i, file := range edited {
+		if editablePlan(file) {
+			index[file.Path] = i
+		}
+	}
+	categories := make(map[string]Category)
+	for _, cat := range opts.taxonomy().flatten() {
+		categories[strings.ToLower(cat.Path)] = cat
+	}
+
+	var problems []string
+	fail := func(line int, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("Zeile %d: ", line)+fmt.Sprintf(format, args...))
+	}
+	listed := make(map[string]int)
+	dests := make(map[string]int)
+	for k, line := range strings.Split(text, "\n") {
+		n := k + 1
+		line = strings.TrimRight(line, "\r")
+		excluded := false
+		if strings.HasPrefix(line, "#") {
+			// A commented out file is excluded; any other comment is skipped.
+			// Only leading blanks go: an empty destination leaves a
+			// trailing tab.
+			line = strings.TrimLeft(strings.TrimPrefix(line, "#"), " ")
+			if _, ok := index[strings.SplitN(line, "\t", 2)[0]]; !ok {
+				continue
+			}
+			excluded = true
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			fail(n, "erwartet Quelle, Kategorie und Ziel, getrennt durch Tabulatoren")
+			continue
+		}
+		source, category, dest := fields[0], strings.TrimSpace(fields[1]), strings.TrimSpace(fields[2])
+		i, ok := index[source]
+		if !ok {
+			fail(n, "%s gehört nicht zum Plan", source)
+			continue
+		}
+		if first, ok := listed[source]; ok {
+			fail(n, "%s steht schon in Zeile %d", filepath.Base(source), first)
+			continue
+		}
+		listed[source] = n
+		entry := &edited[i]
+		entry.Excluded = excluded
+		if excluded {
+			continue
+		}
+
+		changed := false
+		if !opts.Flatten && category != entry.Category {
+			cat, ok := categories[strings.ToLower(category)]
+			if !ok {
+				fail(n, "unbekannte Kategorie %q", category)
+				continue
+			}
+			if cat.Path != entry.Category {
+				entry.setCategory(cat)
+				changed = true
+			}
+		}
+
+		switch {
+		case dest == "" || (dest == opts.planRel(plan[i].Source, plan[i].Dest) && changed):
+			// Computed from the (new) category.
+			entry.manual = ""
+		case dest == opts.planRel(plan[i].Source, plan[i].Dest):
+			// Unchanged; a destination typed earlier stays.
+		default:
+			root, err := opts.planRoot(entry.Source)
+			if err != nil {
+				return plan, err
+			}
+			target, err := expandHome(dest)
+			if err != nil {
+				return plan, err
+			}
+			if strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(filepath.Separator)) {
+				target = filepath.Join(target, entry.Name)
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(root, filepath.FromSlash(target))
+			}
+			target = filepath.Clean(target)
+			if !insideDir(root, target) && (opts.linking() || entry.vars.category.Root == "" || !insideDir(entry.vars.category.Root, target)) {
+				fail(n, "Ziel %s liegt außerhalb von %s", dest, root)
+				continue
+			}
+			if first, ok := dests[target]; ok {
+				fail(n, "Ziel %s ist schon für Zeile %d vorgesehen", dest, first)
+				continue
+			}
+			dests[target] = n
+			entry.manual = target
+		}
+	}
+
+	// Files whose line was deleted are excluded.
+	for source, i := range index {
+		if _, ok := listed[source]; !ok {
+			edited[i].Excluded = true
+		}
+	}
+
+	if len(problems) > 0 {
+		more := ""
+		if len(problems) > maxPlanErrors {
+			more = fmt.Sprintf(" (und %d weitere)", len(problems)-maxPlanErrors)
+			problems = problems[:maxPlanErrors]
+		}
+		return plan, fmt.Errorf("Plan nicht übernommen – %s%s", strings.Join(problems, "; "), more)
+	}
+	if err := replan(edited, opts); err != nil {
+		return plan, err
+	}
+
+	// A destination typed by hand must not be one another file goes to.
+	for i, file := range edited {
+		if file.manual == "" || file.excluded() {
+			continue
+		}
+		for j, other := range edited {
+			if j != i && !other.excluded() && other.target == file.manual {
+				return plan, fmt.Errorf("Plan nicht übernommen – Zeile %d: Ziel %s ist schon für %s vorgesehen", listed[file.Path], opts.planRel(file.Source, file.manual), other.Name)
+			}
+		}
+	}
+	return edited, nil
+}
+
+// insideDir reports whether path lies below dir.
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,246 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPlan(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.pdf", "c.txt"} {
+		writeFile(t, filepath.Join(dir, name), name)
+	}
+	opts := DefaultOptions()
+	plan, _, _, err := buildPlan([]string{dir}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("%d Dateien im Plan, erwartet 3", len(plan))
+	}
+	path, err := writePlan(plan, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := string(data)
+
+	// line is the plan line of a file with the given category and
+	// destination.
+	line := func(name, category, dest string) string {
+		return strings.Join([]string{filepath.Join(dir, name), category, dest}, "\t")
+	}
+	// edit replaces the line of a file.
+	edit := func(text, name, replacement string) string {
+		lines := strings.Split(text, "\n")
+		for i, l := range lines {
+			if strings.HasPrefix(l, filepath.Join(dir, name)+"\t") {
+				lines[i] = replacement
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	byName := func(plan []FilePreview) map[string]FilePreview {
+		files := make(map[string]FilePreview)
+		for _, file := range plan {
+			files[file.Name] = file
+		}
+		return files
+	}
+	original := byName(plan)
+
+	tests := []struct {
+		name  string
+		text  string
+		err   string
+		check func(t *testing.T, files map[string]FilePreview)
+	}{
+		{
+			name: "unverändert",
+			text: written,
+			check: func(t *testing.T, files map[string]FilePreview) {
+				for name, file := range files {
+					if file.Excluded || file.manual != "" || file.Dest != original[name].Dest {
+						t.Errorf("%s verändert: %+v", name, file)
+					}
+				}
+			},
+		},
+		{
+			name: "Windows-Zeilenenden",
+			text: strings.ReplaceAll(written, "\n", "\r\n"),
+			check: func(t *testing.T, files map[string]FilePreview) {
+				for name, file := range files {
+					if file.Excluded || file.Dest != original[name].Dest {
+						t.Errorf("%s verändert: %+v", name, file)
+					}
+				}
+			},
+		},
+		{
+			name: "Zeile gelöscht und auskommentiert",
+			text: edit(edit(written, "c.txt", ""), "a.jpg", "# "+line("a.jpg", original["a.jpg"].Category, "")),
+			check: func(t *testing.T, files map[string]FilePreview) {
+				if !files["a.jpg"].Excluded || !files["c.txt"].Excluded || files["b.pdf"].Excluded {
+					t.Errorf("ausgeschlossen: a %v, b %v, c %v", files["a.jpg"].Excluded, files["b.pdf"].Excluded, files["c.txt"].Excluded)
+				}
+			},
+		},
+		{
+			name: "andere Kategorie",
+			text: edit(written, "b.pdf", line("b.pdf", "bilder", "Dokumente/b.pdf")),
+			check: func(t *testing.T, files map[string]FilePreview) {
+				file := files["b.pdf"]
+				if file.Category != "Bilder" || !file.Override || file.Dest != filepath.Join(dir, "Bilder", "b.pdf") {
+					t.Errorf("b.pdf: Kategorie %s, Ziel %s", file.Category, file.Dest)
+				}
+			},
+		},
+		{
+			name: "Ziel von Hand",
+			text: edit(written, "b.pdf", line("b.pdf", original["b.pdf"].Category, "Steuer/2024/beleg.pdf")),
+			check: func(t *testing.T, files map[string]FilePreview) {
+				if want := filepath.Join(dir, "Steuer", "2024", "beleg.pdf"); files["b.pdf"].Dest != want {
+					t.Errorf("b.pdf: Ziel %s, erwartet %s", files["b.pdf"].Dest, want)
+				}
+			},
+		},
+		{
+			name: "Ordnername mit zwei Punkten",
+			text: edit(written, "b.pdf", line("b.pdf", original["b.pdf"].Category, "..backup/x")),
+			check: func(t *testing.T, files map[string]FilePreview) {
+				if want := filepath.Join(dir, "..backup", "x"); files["b.pdf"].Dest != want {
+					t.Errorf("b.pdf: Ziel %s, erwartet %s", files["b.pdf"].Dest, want)
+				}
+			},
+		},
+		{
+			name: "Ordner als Ziel",
+			text: edit(written, "c.txt", line("c.txt", original["c.txt"].Category, "Notizen/")),
+			check: func(t *testing.T, files map[string]FilePreview) {
+				if want := filepath.Join(dir, "Notizen", "c.txt"); files["c.txt"].Dest != want {
+					t.Errorf("c.txt: Ziel %s, erwartet %s", files["c.txt"].Dest, want)
+				}
+			},
+		},
+		{
+			name: "unbekannte Kategorie",
+			text: edit(written, "b.pdf", line("b.pdf", "Rezepte", "")),
+			err:  `Zeile 10: unbekannte Kategorie "Rezepte"`,
+		},
+		{
+			name: "Ziel außerhalb",
+			text: edit(written, "b.pdf", line("b.pdf", original["b.pdf"].Category, "../b.pdf")),
+			err:  "liegt außerhalb von",
+		},
+		{
+			name: "Ziel doppelt von Hand",
+			text: edit(edit(written, "b.pdf", line("b.pdf", original["b.pdf"].Category, "Alles/x")),
+				"c.txt", line("c.txt", original["c.txt"].Category, "Alles/x")),
+			err: "ist schon für Zeile",
+		},
+		{
+			name: "Ziel einer anderen Datei",
+			text: edit(written, "b.pdf", line("b.pdf", original["b.pdf"].Category, "Bilder/a.jpg")),
+			err:  "ist schon für a.jpg vorgesehen",
+		},
+		{
+			name: "fremde Datei",
+			text: written + line("fremd.doc", "Dokumente", "") + "\n",
+			err:  "gehört nicht zum Plan",
+		},
+		{
+			name: "doppelte Zeile",
+			text: written + line("a.jpg", original["a.jpg"].Category, "") + "\n",
+			err:  "a.jpg steht schon in Zeile",
+		},
+		{
+			name: "fehlende Spalte",
+			text: edit(written, "a.jpg", filepath.Join(dir, "a.jpg")+"\tBilder"),
+			err:  "getrennt durch Tabulatoren",
+		},
+		{
+			name: "viele Fehler",
+			text: written + "x\ny\nz\nw\n",
+			err:  "(und 1 weitere)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := applyPlan(plan, tt.text, opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("applyPlan = %v, erwartet Fehler mit %q", err, tt.err)
+				}
+				if &edited[0] != &plan[0] {
+					t.Error("bei einem Fehler muss der alte Plan zurückkommen")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, byName(edited))
+			// The plan of the preview is left alone.
+			if !equalPlans(plan, byName(plan), original) {
+				t.Error("applyPlan hat den ursprünglichen Plan verändert")
+			}
+		})
+	}
+}
+
+func equalPlans(plan []FilePreview, files, original map[string]FilePreview) bool {
+	for _, file := range plan {
+		want := original[file.Name]
+		if files[file.Name].Dest != want.Dest || files[file.Name].Category != want.Category || files[file.Name].Excluded != want.Excluded {
+			return false
+		}
+	}
+	return true
+}
+
+func TestInsideDir(t *testing.T) {
+	root := filepath.FromSlash("/daten/ziel")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/daten/ziel/a.txt", want: true},
+		{path: "/daten/ziel/..backup/x", want: true},
+		{path: "/daten/ziel/sub/../a.txt", want: true},
+		{path: "/daten/ziel", want: false},
+		{path: "/daten", want: false},
+		{path: "/daten/anders/a.txt", want: false},
+		{path: "/daten/ziel/../a.txt", want: false},
+	}
+	for _, tt := range tests {
+		if got := insideDir(root, filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("insideDir(%q) = %v, erwartet %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPlanRel(t *testing.T) {
+	dir := t.TempDir()
+	opts := DefaultOptions()
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: filepath.Join(dir, "Bilder", "a.jpg"), want: "Bilder/a.jpg"},
+		{path: filepath.Join(dir, "..backup", "x"), want: "..backup/x"},
+		{path: filepath.Join(filepath.Dir(dir), "a.jpg"), want: filepath.Join(filepath.Dir(dir), "a.jpg")},
+	}
+	for _, tt := range tests {
+		if got := opts.planRel(dir, tt.path); got != tt.want {
+			t.Errorf("planRel(%q) = %q, erwartet %q", tt.path, got, tt.want)
+		}
+	}
+}
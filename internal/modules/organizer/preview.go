@@ -0,0 +1,377 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"example/ordi/internal/ignore"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const previewTableHeight = 12
+
+func newPreviewTable() table.Model {
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		BorderBottom(true).
+		Bold(true)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("63")).
+		Bold(false)
+
+	return table.New(
+		table.WithColumns(previewColumns(100, false)),
+		table.WithFocused(true),
+		table.WithHeight(previewTableHeight),
+		table.WithStyles(s),
+	)
+}
+
+// previewColumns splits the available width between the columns; the file
+// and destination columns get what is left. When flattening, the second
+// column shows the folder a file comes from.
+func previewColumns(width int, flatten bool) []table.Column {
+	const category, note = 18, 22
+	second := "Kategorie"
+	if flatten {
+		second = "Ordner"
+	}
+	rest := width - category - note - 8
+	if rest < 40 {
+		rest = 40
+	}
+	return []table.Column{
+		{Title: "Datei", Width: rest * 2 / 5},
+		{Title: second, Width: category},
+		{Title: "Ziel", Width: rest - rest*2/5},
+		{Title: "Hinweis", Width: note},
+	}
+}
+
+// refreshTable rebuilds the rows from the plan, keeping only files that
+// match the filter.
+func (m *Model) refreshTable() {
+	filter := strings.ToLower(strings.TrimSpace(m.filterInput.Value()))
+
+	m.visible = m.visible[:0]
+	var rows []table.Row
+	for i, file := range m.files {
+		if filter != "" &&
+			!strings.Contains(strings.ToLower(file.Rel), filter) &&
+			!strings.Contains(strings.ToLower(file.Category), filter) {
+			continue
+		}
+		m.visible = append(m.visible, i)
+
+		dest := m.displayPath(file.Dest)
+		if file.excluded() {
+			dest = "–"
+		}
+		name := file.Rel
+		if len(m.sources) > 1 {
+			name = filepath.Join(filepath.Base(file.Source), file.Rel)
+		}
+		rows = append(rows, table.Row{name, file.Icon + " " + file.Category, dest, fileNote(file)})
+	}
+
+	m.table.SetRows(rows)
+	if m.table.Cursor() >= len(rows) {
+		m.table.SetCursor(len(rows) - 1)
+	}
+	if m.table.Cursor() < 0 && len(rows) > 0 {
+		m.table.SetCursor(0)
+	}
+}
+
+// fileNote explains in the table why a file needs attention.
+func fileNote(file FilePreview) string {
+	switch {
+	case file.Excluded:
+		return "ausgeschlossen"
+	case file.ExtExcluded:
+		return "Endung ausgeschlossen"
+	case file.CompanionOf != "":
+		return "gehört zu " + filepath.Base(file.CompanionOf)
+	case file.ClashWith != "":
+		return "⚠️ " + file.Action.String() + ", gleicher Name wie " + filepath.Base(filepath.Dir(file.ClashWith)) + "/" + filepath.Base(file.ClashWith)
+	case file.Action != actionMove:
+		return file.Action.String()
+	case file.manual != "":
+		return "Ziel bearbeitet"
+	case file.Override:
+		return "manuell zugeordnet"
+	case file.Suggested > 0:
+		return fmt.Sprintf("gelernt (%.0f %%)", file.Suggested*100)
+	case file.Mismatch:
+		return "⚠️ " + file.Detected
+	}
+	return ""
+}
+
+// selectedFile returns the plan entry under the table cursor.
+func (m *Model) selectedFile() *FilePreview {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.visible) {
+		return nil
+	}
+	return &m.files[m.visible[cursor]]
+}
+
+// selectedUnit returns the plan entry under the table cursor or, for a
+// sidecar, the file it belongs to, so a change applies to both.
+func (m *Model) selectedUnit() *FilePreview {
+	file := m.selectedFile()
+	if file == nil || file.CompanionOf == "" {
+		return file
+	}
+	for i := range m.files {
+		if m.files[i].Path == file.CompanionOf {
+			return &m.files[i]
+		}
+	}
+	return file
+}
+
+func (m Model) updatePreview(msg tea.KeyMsg) (Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.ignoring {
+		switch msg.Type {
+		case tea.KeyEnter:
+			source := m.sources[0]
+			if file := m.selectedFile(); file != nil {
+				source = file.Source
+			}
+			target, err := ignore.Target(source, m.ignoreGlobal)
+			if err == nil {
+				err = ignore.Append(target, m.ignoreInput.Value())
+			}
+			if err != nil {
+				m.Err = err
+				return m, nil
+			}
+			m.ignoring = false
+			m.ignoreInput.Blur()
+			m.Err = nil
+			m.State = stateScanning
+			return m, tea.Batch(m.Spinner.Tick, scanFiles(m.sources, m.Options))
+		case tea.KeyEsc:
+			m.ignoring = false
+			m.ignoreInput.Blur()
+			m.Err = nil
+		case tea.KeyTab:
+			m.ignoreGlobal = !m.ignoreGlobal
+		default:
+			m.ignoreInput, cmd = m.ignoreInput.Update(msg)
+		}
+		return m, cmd
+	}
+
+	if m.namingEvent != "" {
+		switch msg.Type {
+		case tea.KeyEnter:
+			if m.Options.EventNames == nil {
+				m.Options.EventNames = make(map[string]string)
+			}
+			m.Options.EventNames[m.namingEvent] = m.eventInput.Value()
+			m.namingEvent = ""
+			m.eventInput.Blur()
+			return m.replanPreview()
+		case tea.KeyEsc:
+			m.namingEvent = ""
+			m.eventInput.Blur()
+		default:
+			m.eventInput, cmd = m.eventInput.Update(msg)
+		}
+		return m, cmd
+	}
+
+	if m.filtering {
+		switch msg.Type {
+		case tea.KeyEnter:
+			m.filtering = false
+			m.filterInput.Blur()
+		case tea.KeyEsc:
+			m.filtering = false
+			m.filterInput.Blur()
+			m.filterInput.SetValue("")
+			m.refreshTable()
+		default:
+			m.filterInput, cmd = m.filterInput.Update(msg)
+			m.refreshTable()
+		}
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "enter":
+		approved := approvedPlan(m.files)
+		if len(approved) == 0 && len(m.dangling) == 0 {
+			m.Err = fmt.Errorf("Alle Dateien sind ausgeschlossen.")
+			return m, nil
+		}
+		m.Err = nil
+		m.State = stateOrganizing
+		m.progress, m.total = 0, len(approved)
+		m.bytesMoved, m.bytesTotal = 0, 0
+		m.aborting = false
+		var wait tea.Cmd
+		m.run, wait = organizeFiles(m.sources, approved, m.dangling, m.Options)
+		return m, tea.Batch(m.Spinner.Tick, wait)
+
+	case "esc":
+		if m.filterInput.Value() != "" {
+			m.filterInput.SetValue("")
+			m.refreshTable()
+			return m, nil
+		}
+		return m, func() tea.Msg { return BackMsg{} }
+
+	case "/":
+		m.filtering = true
+		return m, m.filterInput.Focus()
+
+	case "left", "h", "right", "l":
+		file := m.selectedUnit()
+		if file == nil || m.Options.Flatten {
+			return m, nil
+		}
+		delta := 1
+		if msg.String() == "left" || msg.String() == "h" {
+			delta = -1
+		}
+		categories := m.Options.taxonomy().flatten()
+		current := 0
+		for i, cat := range categories {
+			if cat.Path == file.Category {
+				current = i
+			}
+		}
+		file.setCategory(categories[wrap(current+delta, len(categories))])
+		return m.replanPreview()
+
+	case "i":
+		m.ignoring = true
+		m.ignoreInput.SetValue("")
+		if file := m.selectedFile(); file != nil {
+			m.ignoreInput.SetValue(filepath.ToSlash(file.Rel))
+			m.ignoreInput.CursorEnd()
+		}
+		return m, m.ignoreInput.Focus()
+
+	case "r":
+		file := m.selectedFile()
+		if file == nil || file.ClashWith == "" {
+			return m, nil
+		}
+		file.KeepBoth = !file.KeepBoth
+		return m.replanPreview()
+
+	case "R":
+		for i := range m.files {
+			if m.files[i].ClashWith != "" {
+				m.files[i].KeepBoth = true
+			}
+		}
+		return m.replanPreview()
+
+	case "n":
+		file := m.selectedUnit()
+		if file == nil || file.vars.event == "" {
+			return m, nil
+		}
+		m.namingEvent = file.vars.event
+		m.eventInput.SetValue(m.Options.eventName(file.vars.event))
+		m.eventInput.CursorEnd()
+		return m, m.eventInput.Focus()
+
+	case "+", "-":
+		if m.Options.EventGap == 0 {
+			return m, nil
+		}
+		delta := 1
+		if msg.String() == "-" {
+			delta = -1
+		}
+		m.Options.EventGap = stepEventGap(m.Options.EventGap, delta)
+		return m.replanPreview()
+
+	case "s":
+		m.showStats = !m.showStats
+		return m, nil
+
+	case "e":
+		if len(m.files) == 0 {
+			return m, nil
+		}
+		m.Err = nil
+		return m, editPlan(m.planPath, m.files, m.Options)
+
+	case "x":
+		file := m.selectedUnit()
+		if file == nil {
+			return m, nil
+		}
+		file.Excluded = !file.Excluded
+		return m.replanPreview()
+
+	case "X":
+		file := m.selectedFile()
+		if file == nil {
+			return m, nil
+		}
+		ext := file.extKey()
+		exclude := !m.excludedExts[ext]
+		if exclude {
+			m.excludedExts[ext] = true
+		} else {
+			delete(m.excludedExts, ext)
+		}
+		for i := range m.files {
+			if m.files[i].extKey() == ext {
+				m.files[i].ExtExcluded = exclude
+			}
+		}
+		return m.replanPreview()
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// replanPreview updates destinations and conflicts after an edit.
+func (m Model) replanPreview() (Model, tea.Cmd) {
+	m.Err = nil
+	if err := replan(m.files, m.Options); err != nil {
+		m.Err = err
+	}
+	m.refreshTable()
+	return m, nil
+}
+
+// countClashes returns the number of files that want the same name as a
+// file from another source.
+func countClashes(plan []FilePreview) int {
+	n := 0
+	for _, file := range plan {
+		if file.ClashWith != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// extLabel names an extension for the list of excluded types.
+func extLabel(ext string) string {
+	if ext == "" {
+		return "(ohne Endung)"
+	}
+	return ext
+}
@@ -0,0 +1,218 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// previewModel returns a model showing the preview of dir, as after a scan.
+func previewModel(t *testing.T, dir string) Model {
+	t.Helper()
+	m := New()
+	m.sources = []string{dir}
+	m.Options.Recursive = true
+	files, ignored, busy, err := buildPlan(m.sources, m.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ = m.Update(ScanCompleteMsg{Files: files, TotalFiles: len(files), Ignored: ignored, Busy: busy})
+	if m.State != statePreview {
+		t.Fatalf("Zustand %v nach dem Scan, erwartet die Vorschau", m.State)
+	}
+	return m
+}
+
+// selectFile puts the table cursor on the file with the relative path rel.
+func selectFile(t *testing.T, m *Model, rel string) {
+	t.Helper()
+	for row, i := range m.visible {
+		if filepath.ToSlash(m.files[i].Rel) == rel {
+			m.table.SetCursor(row)
+			return
+		}
+	}
+	t.Fatalf("%s nicht in der Tabelle", rel)
+}
+
+func fileByRel(t *testing.T, m Model, rel string) FilePreview {
+	t.Helper()
+	for _, file := range m.files {
+		if filepath.ToSlash(file.Rel) == rel {
+			return file
+		}
+	}
+	t.Fatalf("%s nicht im Plan", rel)
+	return FilePreview{}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "left":
+		return tea.KeyMsg{Type: tea.KeyLeft}
+	case "right":
+		return tea.KeyMsg{Type: tea.KeyRight}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestUpdatePreview(t *testing.T) {
+	tests := []struct {
+		name  string
+		rel   string
+		keys  []string
+		check func(t *testing.T, dir string, m Model)
+	}{
+		{
+			name: "Kategorie wechseln",
+			rel:  "a.jpg",
+			keys: []string{"right"},
+			check: func(t *testing.T, dir string, m Model) {
+				file := fileByRel(t, m, "a.jpg")
+				if file.Category != "Bilder/RAW" || !file.Override {
+					t.Errorf("Kategorie %s, manuell %v", file.Category, file.Override)
+				}
+				// The new folder already holds a file of that name.
+				if want := filepath.Join(dir, "Bilder", "RAW", "a (2).jpg"); file.Dest != want || file.Action != actionRename {
+					t.Errorf("Ziel %s (%v), erwartet %s", file.Dest, file.Action, want)
+				}
+			},
+		},
+		{
+			name: "Kategorie hin und zurück",
+			rel:  "a.jpg",
+			keys: []string{"right", "left"},
+			check: func(t *testing.T, dir string, m Model) {
+				file := fileByRel(t, m, "a.jpg")
+				if want := filepath.Join(dir, "Bilder", "a.jpg"); file.Category != "Bilder" || file.Dest != want || file.Action != actionMove {
+					t.Errorf("Kategorie %s, Ziel %s (%v)", file.Category, file.Dest, file.Action)
+				}
+				if note := m.table.SelectedRow()[3]; note != "manuell zugeordnet" {
+					t.Errorf("Hinweis %q", note)
+				}
+			},
+		},
+		{
+			name: "ausschließen",
+			rel:  "b.pdf",
+			keys: []string{"x"},
+			check: func(t *testing.T, dir string, m Model) {
+				if !fileByRel(t, m, "b.pdf").Excluded {
+					t.Error("b.pdf nicht ausgeschlossen")
+				}
+				if row := m.table.SelectedRow(); row[2] != "–" || row[3] != "ausgeschlossen" {
+					t.Errorf("Zeile %q", row)
+				}
+				// The file of the subfolder no longer has to give way.
+				other := fileByRel(t, m, "sub/b.pdf")
+				if want := filepath.Join(dir, "Dokumente", "b.pdf"); other.Dest != want || other.Action != actionMove {
+					t.Errorf("sub/b.pdf: Ziel %s (%v), erwartet %s", other.Dest, other.Action, want)
+				}
+				if len(approvedPlan(m.files)) != len(m.files)-1 {
+					t.Error("ausgeschlossene Datei im Plan für den Lauf")
+				}
+			},
+		},
+		{
+			name: "ausschließen und wieder aufnehmen",
+			rel:  "b.pdf",
+			keys: []string{"x", "x"},
+			check: func(t *testing.T, dir string, m Model) {
+				if fileByRel(t, m, "b.pdf").Excluded {
+					t.Error("b.pdf noch ausgeschlossen")
+				}
+				if other := fileByRel(t, m, "sub/b.pdf"); other.Action != actionRename {
+					t.Errorf("sub/b.pdf: %v, erwartet %v", other.Action, actionRename)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			for _, name := range []string{"a.jpg", "b.pdf", "sub/b.pdf", "c.txt", "Bilder/RAW/a.jpg"} {
+				writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), name)
+			}
+			m := previewModel(t, dir)
+			if other := fileByRel(t, m, "sub/b.pdf"); other.Action != actionRename {
+				t.Fatalf("sub/b.pdf: %v, erwartet %v", other.Action, actionRename)
+			}
+			selectFile(t, &m, tt.rel)
+			for _, k := range tt.keys {
+				m, _ = m.updatePreview(key(k))
+				if m.Err != nil {
+					t.Fatalf("%s: %v", k, m.Err)
+				}
+			}
+			tt.check(t, dir, m)
+		})
+	}
+}
+
+func TestUpdatePreviewManualDest(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.pdf"} {
+		writeFile(t, filepath.Join(dir, name), name)
+	}
+	m := previewModel(t, dir)
+
+	// The plan editor writes the destination; the preview takes it over.
+	path, err := writePlan(m.files, m.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := fileByRel(t, m, "b.pdf")
+	old := b.Path + "\t" + b.Category + "\t" + m.Options.planRel(dir, b.Dest)
+	edited := replaceLine(t, string(data), old, b.Path+"\t"+b.Category+"\tSteuer/beleg.pdf")
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = m.Update(PlanEditedMsg{Path: path})
+	if m.Err != nil {
+		t.Fatal(m.Err)
+	}
+	if want := filepath.Join(dir, "Steuer", "beleg.pdf"); fileByRel(t, m, "b.pdf").Dest != want {
+		t.Fatalf("Ziel %s, erwartet %s", fileByRel(t, m, "b.pdf").Dest, want)
+	}
+	selectFile(t, &m, "b.pdf")
+	if note := m.table.SelectedRow()[3]; note != "Ziel bearbeitet" {
+		t.Errorf("Hinweis %q", note)
+	}
+
+	// The typed destination goes through the conflict policy like any other.
+	writeFile(t, filepath.Join(dir, "Steuer", "beleg.pdf"), "alt")
+	m, _ = m.replanPreview()
+	if file := fileByRel(t, m, "b.pdf"); file.Dest != filepath.Join(dir, "Steuer", "beleg (2).pdf") || file.Action != actionRename {
+		t.Errorf("nach dem Neuplanen: Ziel %s (%v)", file.Dest, file.Action)
+	}
+
+	// Choosing a category drops the typed destination.
+	m, _ = m.updatePreview(key("left"))
+	file := fileByRel(t, m, "b.pdf")
+	if file.manual != "" || filepath.Dir(file.Dest) != categoryDir(dir, file.vars.category) {
+		t.Errorf("Ziel %s liegt nicht im Ordner der Kategorie %s", file.Dest, file.Category)
+	}
+}
+
+func replaceLine(t *testing.T, text, old, replacement string) string {
+	t.Helper()
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == old {
+			lines[i] = replacement
+			return strings.Join(lines, "\n")
+		}
+	}
+	t.Fatalf("Zeile %q nicht im Plan:\n%s", old, text)
+	return ""
+}
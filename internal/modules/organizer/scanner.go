@@ -1,58 +1,160 @@
 package organizer
 
 import (
-	"os"
+	"context"
+	"errors"
+	"fmt"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func scanFiles(dirPath string) tea.Cmd {
+func scanFiles(sources []string, opts Options) tea.Cmd {
 	return func() tea.Msg {
-		files, err := os.ReadDir(dirPath)
+		previews, ignored, busy, err := buildPlan(sources, opts)
 		if err != nil {
 			return ScanCompleteMsg{Err: err}
 		}
 
-		var previews []FilePreview
-		totalFiles := 0
-
-		for _, file := range files {
-			if file.IsDir() {
-				continue
+		msg := ScanCompleteMsg{
+			Files:      previews,
+			TotalFiles: len(previews),
+			Ignored:    ignored,
+			Busy:       busy,
+		}
+		if opts.linking() {
+			target, _ := opts.linkRoot()
+			if msg.Dangling, err = findDanglingLinks(target, opts.Mode); err != nil {
+				return ScanCompleteMsg{Err: err}
 			}
+		}
+		return msg
+	}
+}
 
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
+// organizeRun connects a run in the background with the TUI: progress and
+// the final result arrive on msgs, cancel aborts the run.
+type organizeRun struct {
+	msgs   chan tea.Msg
+	cancel context.CancelFunc
+}
+
+// organizeFiles carries out the plan approved in the preview in the
+// background and removes the dangling links found during the scan. It returns
+// the command that delivers the first message. Every OrganizeProgressMsg must
+// be answered with run.wait() to receive the next one.
+func organizeFiles(sources []string, plan []FilePreview, dangling []JournalMove, opts Options) (*organizeRun, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &organizeRun{msgs: make(chan tea.Msg), cancel: cancel}
+
+	go func() {
+		defer close(run.msgs)
+		defer cancel()
 
-			category := getCategory(file.Name())
-			previews = append(previews, FilePreview{
-				Name:     file.Name(),
-				Category: category.Name,
-				Icon:     category.Icon,
-				Size:     info.Size(),
-			})
-			totalFiles++
+		// The journal belongs to the folder the files were sorted into.
+		root, err := opts.targetRoot(sources[0])
+		if err != nil {
+			run.msgs <- OrganizeCompleteMsg{Err: err}
+			return
+		}
+		stats, journal, err := executePlan(ctx, root, plan, dangling, opts, func(p OrganizeProgressMsg) {
+			run.msgs <- p
+		})
+		if err == nil {
+			// Category changes made in the preview improve the next
+			// suggestions.
+			stats.Learned, stats.LearnErr = learnCorrections(opts.Classifier, plan)
+		}
+		msg := OrganizeCompleteMsg{Stats: stats, Err: err}
+		if errors.Is(err, errAborted) {
+			msg.Aborted = true
+			msg.Err = nil
 		}
+		if journal != nil && (len(journal.Moves) > 0 || len(journal.Dirs) > 0) {
+			msg.Journal = journal
+		}
+		run.msgs <- msg
+	}()
 
-		return ScanCompleteMsg{
-			Files:      previews,
-			TotalFiles: totalFiles,
+	return run, run.wait()
+}
+
+func (r *organizeRun) wait() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-r.msgs
+		if !ok {
+			return nil
 		}
+		return msg
 	}
 }
 
-// organizeFiles organizes files and reports progress
-func organizeFiles(dirPath string) tea.Cmd {
+func scanEmptyDirs(sources []string, opts Options) tea.Cmd {
 	return func() tea.Msg {
-		stats, err := Organize(dirPath)
-		if err != nil {
-			return OrganizeCompleteMsg{Err: err}
+		var all []emptyDir
+		for _, source := range sources {
+			dirs, err := findEmptyDirs(source, opts)
+			if err != nil {
+				return EmptyDirsMsg{Err: err}
+			}
+			all = append(all, dirs...)
 		}
+		return EmptyDirsMsg{Dirs: all}
+	}
+}
 
-		return OrganizeCompleteMsg{
-			Stats: stats,
+func cleanupDirs(journal *Journal, dirs []emptyDir) tea.Cmd {
+	return func() tea.Msg {
+		if journal == nil {
+			return CleanupCompleteMsg{Err: fmt.Errorf("Ohne Journal kann nicht aufgeräumt werden.")}
+		}
+		removed, err := removeEmptyDirs(journal, dirs)
+		return CleanupCompleteMsg{Removed: removed, Err: err}
+	}
+}
+
+func loadHistory() tea.Msg {
+	journals, err := LoadJournals()
+	return HistoryLoadedMsg{Journals: journals, Err: err}
+}
+
+func revertRun(journal Journal) tea.Cmd {
+	return func() tea.Msg {
+		result, err := Revert(&journal)
+		return RevertCompleteMsg{Result: result, Err: err}
+	}
+}
+
+// watchRun connects a watch session in the background with the TUI.
+type watchRun struct {
+	events chan WatchEvent
+	done   chan error
+	cancel context.CancelFunc
+}
+
+// startWatch starts watching dirs and returns the command that delivers the
+// first event. Every WatchEventMsg must be answered with run.wait().
+func startWatch(dirs []string, opts Options) (*watchRun, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &watchRun{
+		events: make(chan WatchEvent),
+		done:   make(chan error, 1),
+		cancel: cancel,
+	}
+
+	go func() {
+		run.done <- Watch(ctx, dirs, opts, run.events)
+	}()
+
+	return run, run.wait()
+}
+
+func (r *watchRun) wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case event := <-r.events:
+			return WatchEventMsg{Event: event}
+		case err := <-r.done:
+			return WatchStoppedMsg{Err: err}
 		}
 	}
 }
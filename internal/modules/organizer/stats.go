@@ -0,0 +1,150 @@
+package organizer
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// largestFiles is how many of the biggest files the statistics list.
+const largestFiles = 5
+
+// Stats describes the files of a plan: their size per category, the largest
+// files and how old they are. It is shown in the preview and after a run and
+// can be exported as JSON to follow how a folder grows.
+type Stats struct {
+	Created    time.Time       `json:"created"`
+	Sources    []string        `json:"sources,omitempty"`
+	Files      int             `json:"files"`
+	Bytes      int64           `json:"bytes"`
+	Categories []CategoryUsage `json:"categories"`
+	Largest    []FileUsage     `json:"largest"`
+	Ages       []AgeUsage      `json:"ages"`
+}
+
+// CategoryUsage is the number and size of the files of one category.
+type CategoryUsage struct {
+	Category string `json:"category"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// FileUsage is a single file of the statistics and where the plan puts it.
+type FileUsage struct {
+	Path     string `json:"path"`
+	Dest     string `json:"dest"`
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// AgeUsage counts the files last modified within an age range.
+type AgeUsage struct {
+	Label string `json:"label"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ageBuckets are the ranges of the age histogram, each up to an age in days;
+// the last one takes everything older.
+var ageBuckets = []struct {
+	label string
+	days  int
+}{
+	{"< 1 Woche", 7},
+	{"< 1 Monat", 30},
+	{"< 6 Monate", 182},
+	{"< 1 Jahr", 365},
+	{"< 2 Jahre", 2 * 365},
+	{"< 5 Jahre", 5 * 365},
+	{"älter", 0},
+}
+
+// planStats computes the statistics of the files a plan sorts into
+// categories; excluded and archived files are left out. Categories follow
+// the order of the taxonomy.
+func planStats(plan []FilePreview, opts Options, now time.Time) Stats {
+	s := Stats{Created: now}
+	for _, bucket := range ageBuckets {
+		s.Ages = append(s.Ages, AgeUsage{Label: bucket.label})
+	}
+
+	byCategory := make(map[string]*CategoryUsage)
+	var files []FileUsage
+	for _, file := range plan {
+		if file.excluded() || file.Action == actionArchive {
+			continue
+		}
+		s.Files++
+		s.Bytes += file.Size
+
+		usage := byCategory[file.Category]
+		if usage == nil {
+			usage = &CategoryUsage{Category: file.Category}
+			byCategory[file.Category] = usage
+		}
+		usage.Files++
+		usage.Bytes += file.Size
+		files = append(files, FileUsage{Path: file.Path, Dest: file.Dest, Category: file.Category, Bytes: file.Size})
+
+		age := &s.Ages[ageBucket(now.Sub(file.vars.modTime))]
+		age.Files++
+		age.Bytes += file.Size
+	}
+
+	for _, category := range opts.taxonomy().categoryOrder() {
+		if usage, ok := byCategory[category]; ok {
+			s.Categories = append(s.Categories, *usage)
+			delete(byCategory, category)
+		}
+	}
+	// When flattening the categories are the folders the files come from.
+	var rest []string
+	for category := range byCategory {
+		rest = append(rest, category)
+	}
+	sort.Strings(rest)
+	for _, category := range rest {
+		s.Categories = append(s.Categories, *byCategory[category])
+	}
+
+	sort.SliceStable(files, func(a, b int) bool { return files[a].Bytes > files[b].Bytes })
+	if len(files) > largestFiles {
+		files = files[:largestFiles]
+	}
+	s.Largest = files
+	return s
+}
+
+// ageBucket returns the index of the age range age falls into.
+func ageBucket(age time.Duration) int {
+	days := int(age.Hours() / 24)
+	for i, bucket := range ageBuckets {
+		if bucket.days > 0 && days < bucket.days {
+			return i
+		}
+	}
+	return len(ageBuckets) - 1
+}
+
+// ScanStats scans sources like the organizer would and returns the
+// statistics of the files found, without changing anything. Since nothing is
+// moved, it does not wait for files that are still being written.
+func ScanStats(sources []string, opts Options) (Stats, error) {
+	opts.readOnly = true
+	plan, _, _, err := buildPlan(sources, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	s := planStats(plan, opts, time.Now())
+	s.Sources = sources
+	return s, nil
+}
+
+// Export writes the statistics as JSON to w.
+func (s Stats) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(s)
+}
@@ -0,0 +1,137 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example/ordi/internal/inuse"
+)
+
+func TestScanStats(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	now := time.Now()
+	day := 24 * time.Hour
+	fixture := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"a.jpg", 100, 2 * day},
+		{"b.jpg", 300, 20 * day},
+		{"c.pdf", 1000, 100 * day},
+		{"d.txt", 50, 400 * day},
+		{"e.mp3", 2000, 3000 * day},
+		{"f.zip", 10, 300 * day},
+		// Just written: the statistics do not wait for it.
+		{"g.log", 500, 0},
+	}
+	for _, f := range fixture {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", f.size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if f.age > 0 {
+			if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	start := time.Now()
+	s, err := ScanStats([]string{dir}, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= inuse.Window {
+		t.Errorf("ScanStats wartete %v auf Dateien in Benutzung", elapsed)
+	}
+
+	if s.Files != 7 || s.Bytes != 3960 {
+		t.Errorf("%d Dateien mit %d Bytes, erwartet 7 mit 3960", s.Files, s.Bytes)
+	}
+	if !reflect.DeepEqual(s.Sources, []string{dir}) {
+		t.Errorf("Quellen %v", s.Sources)
+	}
+
+	// Categories follow the order of the taxonomy.
+	wantCategories := []CategoryUsage{
+		{Category: "Bilder", Files: 2, Bytes: 400},
+		{Category: "Musik", Files: 1, Bytes: 2000},
+		{Category: "Dokumente", Files: 1, Bytes: 1000},
+		{Category: "Dokumente/Texte", Files: 1, Bytes: 50},
+		{Category: "Archive", Files: 1, Bytes: 10},
+		{Category: "Sonstiges", Files: 1, Bytes: 500},
+	}
+	if !reflect.DeepEqual(s.Categories, wantCategories) {
+		t.Errorf("Kategorien = %+v\nerwartet %+v", s.Categories, wantCategories)
+	}
+
+	var largest []string
+	for _, file := range s.Largest {
+		largest = append(largest, filepath.Base(file.Path))
+		if want := filepath.Join(dir, filepath.FromSlash(file.Category), filepath.Base(file.Path)); file.Dest != want {
+			t.Errorf("%s: Ziel %s", file.Path, file.Dest)
+		}
+	}
+	if want := []string{"e.mp3", "c.pdf", "g.log", "b.jpg", "a.jpg"}; !reflect.DeepEqual(largest, want) {
+		t.Errorf("größte Dateien %v, erwartet %v", largest, want)
+	}
+
+	wantAges := map[string]int{
+		"< 1 Woche":  2,
+		"< 1 Monat":  1,
+		"< 6 Monate": 1,
+		"< 1 Jahr":   1,
+		"< 2 Jahre":  1,
+		"< 5 Jahre":  0,
+		"älter":      1,
+	}
+	if len(s.Ages) != len(ageBuckets) {
+		t.Fatalf("%d Altersstufen, erwartet %d", len(s.Ages), len(ageBuckets))
+	}
+	for _, age := range s.Ages {
+		if age.Files != wantAges[age.Label] {
+			t.Errorf("%s: %d Dateien, erwartet %d", age.Label, age.Files, wantAges[age.Label])
+		}
+	}
+}
+
+func TestPlanStatsLeavesOut(t *testing.T) {
+	now := time.Now()
+	plan := []FilePreview{
+		{Path: "a.jpg", Category: "Bilder", Size: 10, vars: templateVars{modTime: now}},
+		{Path: "b.jpg", Category: "Bilder", Size: 20, Excluded: true, vars: templateVars{modTime: now}},
+		{Path: "c.pdf", Category: "Dokumente", Size: 30, ExtExcluded: true, vars: templateVars{modTime: now}},
+		{Path: "d.zip", Category: "Archive", Size: 40, Action: actionArchive, vars: templateVars{modTime: now}},
+	}
+	s := planStats(plan, DefaultOptions(), now)
+	if s.Files != 1 || s.Bytes != 10 || len(s.Categories) != 1 || len(s.Largest) != 1 {
+		t.Errorf("planStats = %+v, erwartet nur a.jpg", s)
+	}
+}
+
+func TestAgeBucket(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{0, "< 1 Woche"},
+		{6 * day, "< 1 Woche"},
+		{7 * day, "< 1 Monat"},
+		{181 * day, "< 6 Monate"},
+		{364 * day, "< 1 Jahr"},
+		{5*365*day - time.Hour, "< 5 Jahre"},
+		{5 * 365 * day, "älter"},
+	}
+	for _, tt := range tests {
+		if got := ageBuckets[ageBucket(tt.age)].label; got != tt.want {
+			t.Errorf("ageBucket(%v) = %s, erwartet %s", tt.age, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package organizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// audioTags holds the tags the music mode sorts by.
+type audioTags struct {
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Title       string
+	Track       int
+}
+
+func (t audioTags) empty() bool {
+	return t.Artist == "" && t.AlbumArtist == "" && t.Album == "" && t.Title == ""
+}
+
+// merge fills fields that are still empty from other.
+func (t *audioTags) merge(other audioTags) {
+	if t.Artist == "" {
+		t.Artist = other.Artist
+	}
+	if t.AlbumArtist == "" {
+		t.AlbumArtist = other.AlbumArtist
+	}
+	if t.Album == "" {
+		t.Album = other.Album
+	}
+	if t.Title == "" {
+		t.Title = other.Title
+	}
+	if t.Track == 0 {
+		t.Track = other.Track
+	}
+}
+
+var errNoTags = errors.New("keine Tags")
+
+// readTags reads ID3v2/ID3v1 (MP3), Vorbis comments (FLAC, Ogg Vorbis,
+// Opus) and iTunes atoms (M4A). The format is taken from the content, not the
+// name.
+func readTags(path string) (audioTags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return audioTags{}, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 12)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return readFLACTags(f)
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return readOggTags(f)
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return readMP4Tags(f)
+	}
+
+	// MP3: ID3v2 at the start, ID3v1 at the end; either may be missing.
+	var tags audioTags
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		if v2, err := readID3v2(f); err == nil {
+			tags = v2
+		}
+	}
+	if v1, err := readID3v1(f); err == nil {
+		tags.merge(v1)
+	}
+	if tags.empty() {
+		return tags, errNoTags
+	}
+	return tags, nil
+}
+
+// readID3v2 parses the tag at the start of f (versions 2.2 to 2.4).
+func readID3v2(f *os.File) (audioTags, error) {
+	header := make([]byte, 10)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return audioTags{}, err
+	}
+	version, flags := header[3], header[5]
+	size := syncsafe(header[6:10])
+	if version < 2 || version > 4 || size > 16<<20 {
+		return audioTags{}, errNoTags
+	}
+
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, 10); err != nil && !errors.Is(err, io.EOF) {
+		return audioTags{}, err
+	}
+	if flags&0x80 != 0 && version < 4 {
+		data = bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	if flags&0x40 != 0 && version >= 3 && len(data) >= 4 {
+		// Skip the extended header.
+		ext := int(binary.BigEndian.Uint32(data[:4]))
+		if version == 3 {
+			ext += 4
+		} else {
+			ext = int(syncsafe(data[:4]))
+		}
+		if ext > len(data) {
+			return audioTags{}, errNoTags
+		}
+		data = data[ext:]
+	}
+
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	var tags audioTags
+	for len(data) >= headerLen && data[0] != 0 {
+		id := string(data[:idLen])
+		var frameSize int
+		switch version {
+		case 2:
+			frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+		default:
+			frameSize = int(syncsafe(data[4:8]))
+		}
+		if frameSize <= 0 || headerLen+frameSize > len(data) {
+			break
+		}
+		value := data[headerLen : headerLen+frameSize]
+		data = data[headerLen+frameSize:]
+
+		switch id {
+		case "TPE1", "TP1":
+			tags.Artist = id3Text(value)
+		case "TPE2", "TP2":
+			tags.AlbumArtist = id3Text(value)
+		case "TALB", "TAL":
+			tags.Album = id3Text(value)
+		case "TIT2", "TT2":
+			tags.Title = id3Text(value)
+		case "TRCK", "TRK":
+			tags.Track = trackNumber(id3Text(value))
+		}
+	}
+	return tags, nil
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7F)<<21 | uint32(b[1]&0x7F)<<14 | uint32(b[2]&0x7F)<<7 | uint32(b[3]&0x7F)
+}
+
+// id3Text decodes a text frame. Multiple values (ID3v2.4) are joined.
+func id3Text(frame []byte) string {
+	if len(frame) < 1 {
+		return ""
+	}
+	encoding, text := frame[0], frame[1:]
+
+	var s string
+	switch encoding {
+	case 1, 2:
+		s = decodeUTF16(text, encoding == 2)
+	case 3:
+		s = string(text)
+	default:
+		s = latin1(text)
+	}
+	s = strings.TrimRight(s, "\x00")
+	return strings.TrimSpace(strings.ReplaceAll(s, "\x00", ", "))
+}
+
+// decodeUTF16 decodes UTF-16 text with an optional byte order mark.
+func decodeUTF16(b []byte, bigEndian bool) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	var units []uint16
+	for len(b) >= 2 {
+		switch {
+		case b[0] == 0xFF && b[1] == 0xFE:
+			order = binary.LittleEndian
+		case b[0] == 0xFE && b[1] == 0xFF:
+			order = binary.BigEndian
+		default:
+			units = append(units, order.Uint16(b))
+		}
+		b = b[2:]
+	}
+	return string(utf16.Decode(units))
+}
+
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// readID3v1 reads the fixed-size tag in the last 128 bytes.
+func readID3v1(f *os.File) (audioTags, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() < 128 {
+		return audioTags{}, errNoTags
+	}
+	tag := make([]byte, 128)
+	if _, err := f.ReadAt(tag, info.Size()-128); err != nil {
+		return audioTags{}, err
+	}
+	if !bytes.HasPrefix(tag, []byte("TAG")) {
+		return audioTags{}, errNoTags
+	}
+
+	field := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.TrimSpace(latin1(b))
+	}
+	tags := audioTags{
+		Title:  field(tag[3:33]),
+		Artist: field(tag[33:63]),
+		Album:  field(tag[63:93]),
+	}
+	// ID3v1.1 stores the track in the last byte of the comment.
+	if tag[125] == 0 && tag[126] != 0 {
+		tags.Track = int(tag[126])
+	}
+	return tags, nil
+}
+
+// readFLACTags looks for the VORBIS_COMMENT block among the metadata blocks
+// following the "fLaC" marker.
+func readFLACTags(f *os.File) (audioTags, error) {
+	offset := int64(4)
+	for {
+		header := make([]byte, 4)
+		if _, err := f.ReadAt(header, offset); err != nil {
+			return audioTags{}, errNoTags
+		}
+		last, typ := header[0]&0x80 != 0, header[0]&0x7F
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		offset += 4
+
+		if typ == 4 {
+			block := make([]byte, length)
+			if _, err := f.ReadAt(block, offset); err != nil {
+				return audioTags{}, err
+			}
+			return parseVorbisComment(block)
+		}
+		if last {
+			return audioTags{}, errNoTags
+		}
+		offset += length
+	}
+}
+
+// readOggTags reassembles the second packet of the stream, which holds the
+// comments of Vorbis and Opus files.
+func readOggTags(f *os.File) (audioTags, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return audioTags{}, err
+	}
+
+	var packets [][]byte
+	var current []byte
+	read := 0
+	for len(packets) < 2 && read < 4<<20 {
+		header := make([]byte, 27)
+		if _, err := io.ReadFull(f, header); err != nil || !bytes.HasPrefix(header, []byte("OggS")) {
+			return audioTags{}, errNoTags
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(f, segments); err != nil {
+			return audioTags{}, errNoTags
+		}
+		for _, lacing := range segments {
+			segment := make([]byte, lacing)
+			if _, err := io.ReadFull(f, segment); err != nil {
+				return audioTags{}, errNoTags
+			}
+			current = append(current, segment...)
+			read += int(lacing)
+			if lacing < 255 {
+				packets = append(packets, current)
+				current = nil
+			}
+		}
+	}
+	if len(packets) < 2 {
+		return audioTags{}, errNoTags
+	}
+
+	packet := packets[1]
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		return parseVorbisComment(packet[7:])
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		return parseVorbisComment(packet[8:])
+	}
+	return audioTags{}, errNoTags
+}
+
+// parseVorbisComment reads the little-endian "KEY=value" list shared by
+// FLAC and Ogg.
+func parseVorbisComment(b []byte) (audioTags, error) {
+	next := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n > len(b)-4 {
+			return nil, false
+		}
+		value := b[4 : 4+n]
+		b = b[4+n:]
+		return value, true
+	}
+
+	if _, ok := next(); !ok { // vendor string
+		return audioTags{}, errNoTags
+	}
+	if len(b) < 4 {
+		return audioTags{}, errNoTags
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+
+	var tags audioTags
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			break
+		}
+		key, value, found := strings.Cut(string(comment), "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToUpper(key) {
+		case "ARTIST":
+			if tags.Artist == "" {
+				tags.Artist = value
+			}
+		case "ALBUMARTIST", "ALBUM ARTIST":
+			tags.AlbumArtist = value
+		case "ALBUM":
+			tags.Album = value
+		case "TITLE":
+			tags.Title = value
+		case "TRACKNUMBER":
+			tags.Track = trackNumber(value)
+		}
+	}
+	return tags, nil
+}
+
+// readMP4Tags walks moov/udta/meta/ilst. The atoms are read with ReadAt, so
+// a large mdat before moov is skipped without reading it.
+func readMP4Tags(f *os.File) (audioTags, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return audioTags{}, err
+	}
+
+	moov, ok := findAtom(f, 0, info.Size(), "moov")
+	if !ok {
+		return audioTags{}, errNoTags
+	}
+	udta, ok := findAtom(f, moov.start, moov.end, "udta")
+	if !ok {
+		return audioTags{}, errNoTags
+	}
+	meta, ok := findAtom(f, udta.start, udta.end, "meta")
+	if !ok {
+		return audioTags{}, errNoTags
+	}
+	// meta is a full box: version and flags come before the children.
+	ilst, ok := findAtom(f, meta.start+4, meta.end, "ilst")
+	if !ok || ilst.end-ilst.start > 16<<20 {
+		return audioTags{}, errNoTags
+	}
+
+	data := make([]byte, ilst.end-ilst.start)
+	if _, err := f.ReadAt(data, ilst.start); err != nil {
+		return audioTags{}, err
+	}
+
+	var tags audioTags
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			break
+		}
+		name, item := string(data[4:8]), data[8:size]
+		data = data[size:]
+
+		// Each item holds a "data" atom: size, type, 4 bytes type
+		// indicator, 4 bytes locale, then the value.
+		if len(item) < 16 || string(item[4:8]) != "data" {
+			continue
+		}
+		dataSize := int(binary.BigEndian.Uint32(item))
+		if dataSize < 16 || dataSize > len(item) {
+			continue
+		}
+		value := item[16:dataSize]
+
+		switch name {
+		case "\xa9ART":
+			tags.Artist = strings.TrimSpace(string(value))
+		case "aART":
+			tags.AlbumArtist = strings.TrimSpace(string(value))
+		case "\xa9alb":
+			tags.Album = strings.TrimSpace(string(value))
+		case "\xa9nam":
+			tags.Title = strings.TrimSpace(string(value))
+		case "trkn":
+			if len(value) >= 4 {
+				tags.Track = int(binary.BigEndian.Uint16(value[2:4]))
+			}
+		}
+	}
+	return tags, nil
+}
+
+type atom struct {
+	start, end int64
+}
+
+// findAtom returns the content range of the first atom named name between
+// start and end.
+func findAtom(f *os.File, start, end int64, name string) (atom, bool) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return atom{}, false
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerLen := int64(8)
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return atom{}, false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || offset+size > end {
+			return atom{}, false
+		}
+		if string(header[4:8]) == name {
+			return atom{start: offset + headerLen, end: offset + size}, true
+		}
+		offset += size
+	}
+	return atom{}, false
+}
+
+// trackNumber parses "7" and "7/12".
+func trackNumber(s string) int {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
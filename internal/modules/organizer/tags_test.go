@@ -0,0 +1,246 @@
+package organizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// id3v2 builds a tag of the given version from id/value frames; the values
+// already carry their encoding byte.
+func id3v2(version byte, frames ...string) []byte {
+	var body []byte
+	for i := 0; i < len(frames); i += 2 {
+		id, value := frames[i], frames[i+1]
+		body = append(body, id...)
+		switch version {
+		case 2:
+			n := len(value)
+			body = append(body, byte(n>>16), byte(n>>8), byte(n))
+		case 3:
+			body = binary.BigEndian.AppendUint32(body, uint32(len(value)))
+			body = append(body, 0, 0)
+		default:
+			body = append(body, syncsafeBytes(len(value))...)
+			body = append(body, 0, 0)
+		}
+		body = append(body, value...)
+	}
+	// Padding, as written by most taggers.
+	body = append(body, make([]byte, 16)...)
+
+	tag := []byte{'I', 'D', '3', version, 0, 0}
+	tag = append(tag, syncsafeBytes(len(body))...)
+	return append(tag, body...)
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// id3v1 builds the 128 byte tag with a track number (ID3v1.1).
+func id3v1(title, artist, album string, track byte) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:33], title)
+	copy(tag[33:63], artist)
+	copy(tag[63:93], album)
+	tag[126] = track
+	return tag
+}
+
+// utf16Text is an ID3 text value in UTF-16 with byte order mark.
+func utf16Text(s string) string {
+	b := []byte{1, 0xFF, 0xFE}
+	for _, r := range s {
+		b = binary.LittleEndian.AppendUint16(b, uint16(r))
+	}
+	return string(b)
+}
+
+func vorbisComment(comments ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 4)
+	b = append(b, "test"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, c := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+	return b
+}
+
+func flacFile(comments ...string) []byte {
+	b := []byte("fLaC")
+	// STREAMINFO, then the comments as the last block.
+	b = append(b, 0, 0, 0, 34)
+	b = append(b, make([]byte, 34)...)
+	block := vorbisComment(comments...)
+	n := len(block)
+	b = append(b, 0x80|4, byte(n>>16), byte(n>>8), byte(n))
+	return append(b, block...)
+}
+
+// oggPage wraps one packet in a page; packets longer than 255 bytes span
+// several lacing values.
+func oggPage(packet []byte) []byte {
+	var lacing []byte
+	n := len(packet)
+	for n >= 255 {
+		lacing = append(lacing, 255)
+		n -= 255
+	}
+	lacing = append(lacing, byte(n))
+	page := append([]byte("OggS"), make([]byte, 22)...)
+	page = append(page, byte(len(lacing)))
+	page = append(page, lacing...)
+	return append(page, packet...)
+}
+
+func oggFile(head, magic string, comments ...string) []byte {
+	b := oggPage([]byte(head))
+	return append(b, oggPage(append([]byte(magic), vorbisComment(comments...)...))...)
+}
+
+func mp4Atom(name string, children ...[]byte) []byte {
+	var body []byte
+	for _, c := range children {
+		body = append(body, c...)
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	b = append(b, name...)
+	return append(b, body...)
+}
+
+func mp4Item(name string, value []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, uint32(16+len(value)))
+	data = append(data, "data"...)
+	data = append(data, 0, 0, 0, 1, 0, 0, 0, 0)
+	data = append(data, value...)
+	return mp4Atom(name, data)
+}
+
+func mp4File() []byte {
+	ftyp := mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	// A large mdat before moov is skipped.
+	mdat := mp4Atom("mdat", make([]byte, 4096))
+	ilst := mp4Atom("ilst",
+		mp4Item("\xa9ART", []byte("Daft Punk")),
+		mp4Item("\xa9alb", []byte("Discovery")),
+		mp4Item("\xa9nam", []byte("One More Time")),
+		mp4Item("trkn", []byte{0, 0, 0, 1, 0, 14, 0, 0}),
+	)
+	meta := mp4Atom("meta", append([]byte{0, 0, 0, 0}, mp4Atom("hdlr", make([]byte, 25))...), ilst)
+	moov := mp4Atom("moov", mp4Atom("mvhd", make([]byte, 100)), mp4Atom("udta", meta))
+	return append(append(ftyp, mdat...), moov...)
+}
+
+func TestReadTags(t *testing.T) {
+	audio := make([]byte, 512)
+	tests := []struct {
+		name    string
+		content []byte
+		want    audioTags
+		err     error
+	}{
+		{
+			name: "ID3v2.3",
+			content: append(id3v2(3,
+				"TPE1", "\x00Die \xc4rzte",
+				"TALB", "\x00Ger\xe4usch",
+				"TIT2", utf16Text("Unrockbar"),
+				"TRCK", "\x003/15",
+			), audio...),
+			want: audioTags{Artist: "Die Ärzte", Album: "Geräusch", Title: "Unrockbar", Track: 3},
+		},
+		{
+			name: "ID3v2.4 mit mehreren Werten",
+			content: append(id3v2(4,
+				"TPE1", "\x03Queen\x00David Bowie",
+				"TPE2", "\x03Queen",
+				"TALB", "\x03Hot Space",
+				"TIT2", "\x03Under Pressure",
+			), audio...),
+			want: audioTags{Artist: "Queen, David Bowie", AlbumArtist: "Queen", Album: "Hot Space", Title: "Under Pressure"},
+		},
+		{
+			name:    "ID3v2.2",
+			content: append(id3v2(2, "TP1", "\x00Kraftwerk", "TT2", "\x00Autobahn", "TRK", "\x001"), audio...),
+			want:    audioTags{Artist: "Kraftwerk", Title: "Autobahn", Track: 1},
+		},
+		{
+			name:    "ID3v1.1",
+			content: append(append([]byte(nil), audio...), id3v1("Da Da Da", "Trio", "Trio", 4)...),
+			want:    audioTags{Artist: "Trio", Album: "Trio", Title: "Da Da Da", Track: 4},
+		},
+		{
+			name: "ID3v1 ergänzt ID3v2",
+			content: append(append(id3v2(3, "TIT2", "\x00Jein"), audio...),
+				id3v1("Anderer Titel", "Fettes Brot", "Au\xdfen Top Hits", 2)...),
+			want: audioTags{Artist: "Fettes Brot", Album: "Außen Top Hits", Title: "Jein", Track: 2},
+		},
+		{
+			name:    "FLAC",
+			content: flacFile("artist=Beatsteaks", "ALBUM=Smack Smash", "TITLE=Hand in Hand", "TRACKNUMBER=02"),
+			want:    audioTags{Artist: "Beatsteaks", Album: "Smack Smash", Title: "Hand in Hand", Track: 2},
+		},
+		{
+			name:    "Ogg Vorbis",
+			content: oggFile("\x01vorbis", "\x03vorbis", "ARTIST=Erster", "ARTIST=Zweiter", "ALBUM ARTIST=Diverse", "TITLE=Lied"),
+			want:    audioTags{Artist: "Erster", AlbumArtist: "Diverse", Title: "Lied"},
+		},
+		{
+			name:    "Opus",
+			content: oggFile("OpusHead", "OpusTags", "ARTIST=Moderat", "TITLE=Bad Kingdom"),
+			want:    audioTags{Artist: "Moderat", Title: "Bad Kingdom"},
+		},
+		{
+			name:    "M4A",
+			content: mp4File(),
+			want:    audioTags{Artist: "Daft Punk", Album: "Discovery", Title: "One More Time", Track: 1},
+		},
+		{
+			name:    "ohne Tags",
+			content: audio,
+			err:     errNoTags,
+		},
+		{
+			name:    "leere ID3v2-Rahmen",
+			content: append(id3v2(3), audio...),
+			err:     errNoTags,
+		},
+		{
+			name:    "abgeschnittenes FLAC",
+			content: []byte("fLaC\x00\x00"),
+			err:     errNoTags,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "musik")
+			writeFile(t, path, string(tt.content))
+			got, err := readTags(path)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("readTags = %+v, %v, erwartet Fehler %v", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("readTags = %+v, erwartet %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackNumber(t *testing.T) {
+	tests := map[string]int{"7": 7, "07/12": 7, " 3/10 ": 3, "": 0, "A1": 0, "-2": 0}
+	for s, want := range tests {
+		if got := trackNumber(s); got != want {
+			t.Errorf("trackNumber(%q) = %d, erwartet %d", s, got, want)
+		}
+	}
+}
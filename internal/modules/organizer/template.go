@@ -0,0 +1,250 @@
+package organizer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example/ordi/internal/places"
+)
+
+// DefaultTemplate puts every file directly into its category folder.
+const DefaultTemplate = "{category}/{name}{ext}"
+
+// templatePresets are offered on the options screen; any other template can
+// be typed in.
+var templatePresets = []string{
+	DefaultTemplate,
+	"{category}/{year}/{month}/{name}{ext}",
+	"{category}/{exif_year}/{exif_year}-{exif_month}-{exif_day}/{name}{ext}",
+	"{category}/{place}/{exif_year}-{exif_month}/{name}{ext}",
+	"{category}/{size}/{name}{ext}",
+	"{category}/{year}/{seq}_{name}{ext}",
+}
+
+// templatePlaceholders lists the supported placeholders with a description
+// for the help text.
+var templatePlaceholders = []struct{ name, desc string }{
+	{"category", "Kategorie-Ordner"},
+	{"name", "Dateiname ohne Endung"},
+	{"ext", "Endung inkl. Punkt"},
+	{"year", "Jahr der letzten Änderung"},
+	{"month", "Monat der letzten Änderung"},
+	{"day", "Tag der letzten Änderung"},
+	{"exif_year", "Aufnahmejahr (EXIF, sonst Änderung)"},
+	{"exif_month", "Aufnahmemonat"},
+	{"exif_day", "Aufnahmetag"},
+	{"place", "Aufnahmeort (nächste Stadt, GPS)"},
+	{"size", "Größenklasse (klein/mittel/groß)"},
+	{"seq", "laufende Nummer"},
+}
+
+type templatePart struct {
+	literal     string
+	placeholder string
+}
+
+// pathTemplate describes where a file ends up. Paths are always relative: if
+// the template starts with "{category}/" the rest is resolved inside the
+// category folder (which may have its own destination), otherwise inside the
+// organized folder.
+type pathTemplate struct {
+	raw        string
+	inCategory bool
+	parts      []templatePart
+	usesExif   bool
+	usesPlace  bool
+}
+
+func parseTemplate(raw string) (*pathTemplate, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("Die Vorlage ist leer.")
+	}
+	if strings.Contains(raw, `\`) {
+		return nil, fmt.Errorf("Bitte \"/\" als Trennzeichen verwenden.")
+	}
+	if strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "~") || filepath.VolumeName(raw) != "" || (len(raw) > 1 && raw[1] == ':') {
+		return nil, fmt.Errorf("Die Vorlage darf kein absoluter Pfad sein.")
+	}
+	if strings.HasSuffix(raw, "/") {
+		return nil, fmt.Errorf("Die Vorlage muss mit einem Dateinamen enden.")
+	}
+
+	t := &pathTemplate{raw: raw}
+	rest := raw
+	if strings.HasPrefix(rest, "{category}/") {
+		t.inCategory = true
+		rest = strings.TrimPrefix(rest, "{category}/")
+	}
+
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		close := strings.IndexByte(rest, '}')
+		if open < 0 {
+			if close >= 0 {
+				return nil, fmt.Errorf("Unerwartetes \"}\" in der Vorlage.")
+			}
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if close >= 0 && close < open {
+			return nil, fmt.Errorf("Unerwartetes \"}\" in der Vorlage.")
+		}
+		if open > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("Nicht geschlossener Platzhalter in der Vorlage.")
+		}
+		name := rest[open+1 : open+end]
+		if !isPlaceholder(name) {
+			return nil, fmt.Errorf("Unbekannter Platzhalter {%s}.", name)
+		}
+		if strings.HasPrefix(name, "exif_") {
+			t.usesExif = true
+		}
+		if name == "place" {
+			t.usesExif, t.usesPlace = true, true
+		}
+		t.parts = append(t.parts, templatePart{placeholder: name})
+		rest = rest[open+end+1:]
+	}
+
+	var literal strings.Builder
+	for _, part := range t.parts {
+		literal.WriteString(part.literal)
+		literal.WriteString("{}")
+	}
+	segments := strings.Split(literal.String(), "/")
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("Die Vorlage darf keine leeren Ordner, \".\" oder \"..\" enthalten.")
+		}
+	}
+
+	fileName := raw[strings.LastIndex(raw, "/")+1:]
+	if !strings.Contains(fileName, "{name}") && !strings.Contains(fileName, "{seq}") {
+		return nil, fmt.Errorf("Der Dateiname muss {name} oder {seq} enthalten.")
+	}
+
+	return t, nil
+}
+
+func isPlaceholder(name string) bool {
+	for _, p := range templatePlaceholders {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// templateVars are the values of one file.
+type templateVars struct {
+	category Category
+	name     string
+	ext      string
+	modTime  time.Time
+	taken    time.Time
+	size     int64
+	seq      int
+	tags     *audioTags
+	// event is the default name of the photo event the file belongs to.
+	event string
+	// gps and place are the position of a photo and the name of its place.
+	gps   *places.Point
+	place string
+}
+
+// resolve returns the absolute destination of a file. It fails if the result
+// would end up outside the target folder.
+func (t *pathTemplate) resolve(root string, v templateVars) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.placeholder == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+		b.WriteString(t.value(part.placeholder, v))
+	}
+
+	base := root
+	if t.inCategory {
+		base = categoryDir(root, v.category)
+	}
+	dest := filepath.Join(base, filepath.FromSlash(b.String()))
+
+	rel, err := filepath.Rel(base, dest)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("Vorlage %q führt für %s aus dem Zielordner hinaus", t.raw, v.name+v.ext)
+	}
+	return dest, nil
+}
+
+func (t *pathTemplate) value(placeholder string, v templateVars) string {
+	taken := v.shotTime()
+
+	switch placeholder {
+	case "category":
+		return v.category.Path
+	case "name":
+		return sanitizeSegment(v.name)
+	case "ext":
+		return sanitizeSegment(v.ext)
+	case "year":
+		return v.modTime.Format("2006")
+	case "month":
+		return v.modTime.Format("01")
+	case "day":
+		return v.modTime.Format("02")
+	case "exif_year":
+		return taken.Format("2006")
+	case "exif_month":
+		return taken.Format("01")
+	case "exif_day":
+		return taken.Format("02")
+	case "place":
+		return placeFolder(v.place)
+	case "size":
+		return sizeBucket(v.size)
+	case "seq":
+		return fmt.Sprintf("%04d", v.seq)
+	}
+	return ""
+}
+
+func sizeBucket(size int64) string {
+	switch {
+	case size < 1<<20:
+		return "klein"
+	case size < 100<<20:
+		return "mittel"
+	default:
+		return "groß"
+	}
+}
+
+// sanitizeSegment keeps values from introducing extra path levels.
+func sanitizeSegment(s string) string {
+	return strings.NewReplacer("/", "_", `\`, "_").Replace(s)
+}
+
+// splitName splits a file name into base name and extension, keeping
+// compound extensions such as ".tar.gz" together.
+func splitName(fileName string) (string, string) {
+	lower := strings.ToLower(fileName)
+	for _, compound := range []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst"} {
+		if strings.HasSuffix(lower, compound) && len(fileName) > len(compound) {
+			cut := len(fileName) - len(compound)
+			return fileName[:cut], fileName[cut:]
+		}
+	}
+	ext := filepath.Ext(fileName)
+	if ext == fileName {
+		return fileName, ""
+	}
+	return strings.TrimSuffix(fileName, ext), ext
+}
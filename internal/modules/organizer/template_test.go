@@ -0,0 +1,130 @@
+package organizer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		raw        string
+		err        string // part of the expected error, "" if valid
+		inCategory bool
+		usesExif   bool
+	}{
+		{raw: DefaultTemplate, inCategory: true},
+		{raw: "{category}/{year}/{month}/{name}{ext}", inCategory: true},
+		{raw: "{category}/{exif_year}/{name}{ext}", inCategory: true, usesExif: true},
+		{raw: "{category}/{place}/{name}{ext}", inCategory: true, usesExif: true},
+		{raw: "Sortiert/{year}/{seq}{ext}"},
+		{raw: "  {category}/{name}{ext}  ", inCategory: true},
+		{raw: "", err: "leer"},
+		{raw: `{category}\{name}{ext}`, err: "Trennzeichen"},
+		{raw: "/tmp/{name}{ext}", err: "absoluter Pfad"},
+		{raw: "~/{name}{ext}", err: "absoluter Pfad"},
+		{raw: "C:/{name}{ext}", err: "absoluter Pfad"},
+		{raw: "{category}/{year}/", err: "Dateinamen enden"},
+		{raw: "{category}/{jahr}/{name}{ext}", err: "Unbekannter Platzhalter {jahr}"},
+		{raw: "{category}/{year/{name}{ext}", err: "Unbekannter Platzhalter"},
+		{raw: "{category}/{name{ext}", err: "Unbekannter Platzhalter"},
+		{raw: "{category}/{name}{ext", err: "Nicht geschlossener Platzhalter"},
+		{raw: "{category}/year}/{name}{ext}", err: "Unerwartetes"},
+		{raw: "{category}//{name}{ext}", err: "leeren Ordner"},
+		{raw: "{category}/../{name}{ext}", err: "\"..\""},
+		{raw: "{category}/{name}/datei{ext}", err: "{name} oder {seq}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.raw)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("parseTemplate(%q) = %v, erwartet Fehler mit %q", tt.raw, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTemplate(%q): %v", tt.raw, err)
+			}
+			if tmpl.inCategory != tt.inCategory || tmpl.usesExif != tt.usesExif {
+				t.Errorf("parseTemplate(%q): inCategory %v, usesExif %v", tt.raw, tmpl.inCategory, tmpl.usesExif)
+			}
+		})
+	}
+}
+
+func TestResolveTemplate(t *testing.T) {
+	root := filepath.FromSlash("/daten")
+	photos := defaultTaxonomy.getCategory("foto.jpg")
+	own := photos
+	own.Root = filepath.FromSlash("/bilder")
+	vars := templateVars{
+		category: photos,
+		name:     "IMG_0042",
+		ext:      ".JPG",
+		modTime:  time.Date(2024, 3, 9, 12, 0, 0, 0, time.Local),
+		taken:    time.Date(2023, 12, 24, 18, 0, 0, 0, time.Local),
+		size:     5 << 20,
+		seq:      7,
+		place:    "Rom, Italien",
+	}
+	tests := []struct {
+		raw  string
+		vars func(v templateVars) templateVars
+		want string
+		err  bool
+	}{
+		{raw: DefaultTemplate, want: "/daten/Bilder/IMG_0042.JPG"},
+		{raw: "{category}/{year}/{month}/{day}/{name}{ext}", want: "/daten/Bilder/2024/03/09/IMG_0042.JPG"},
+		{raw: "{category}/{exif_year}-{exif_month}-{exif_day}/{name}{ext}", want: "/daten/Bilder/2023-12-24/IMG_0042.JPG"},
+		{raw: "{category}/{size}/{seq}_{name}{ext}", want: "/daten/Bilder/mittel/0007_IMG_0042.JPG"},
+		{raw: "{category}/{place}/{name}{ext}", want: "/daten/Bilder/Rom, Italien/IMG_0042.JPG"},
+		{raw: "Alle/{category}/{name}{ext}", want: "/daten/Alle/Bilder/IMG_0042.JPG"},
+		{
+			raw:  "{category}/{exif_year}/{name}{ext}",
+			vars: func(v templateVars) templateVars { v.taken = time.Time{}; return v },
+			want: "/daten/Bilder/2024/IMG_0042.JPG",
+		},
+		{
+			raw:  DefaultTemplate,
+			vars: func(v templateVars) templateVars { v.category = own; return v },
+			want: "/bilder/IMG_0042.JPG",
+		},
+		{
+			raw:  DefaultTemplate,
+			vars: func(v templateVars) templateVars { v.name = "../../etc/passwd"; return v },
+			want: "/daten/Bilder/.._.._etc_passwd.JPG",
+		},
+		{
+			raw:  "{category}/{name}",
+			vars: func(v templateVars) templateVars { v.name = ".."; return v },
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := vars
+			if tt.vars != nil {
+				v = tt.vars(v)
+			}
+			got, err := tmpl.resolve(root, v)
+			if tt.err {
+				if err == nil {
+					t.Errorf("resolve = %s, erwartet Fehler", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.FromSlash(tt.want); got != want {
+				t.Errorf("resolve = %s, erwartet %s", got, want)
+			}
+		})
+	}
+}
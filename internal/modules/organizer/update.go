@@ -3,6 +3,8 @@ package organizer
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,20 +20,23 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			switch msg.Type {
 			case tea.KeyEnter:
 				m.Path = m.TextInput.Value()
+				m.sources = splitFolders(m.Path)
 
-				if m.Path == "" {
+				if len(m.sources) == 0 {
 					m.Err = fmt.Errorf("Bitte gib einen Pfad ein.")
 					return m, nil
 				}
 
-				if _, err := os.Stat(m.Path); os.IsNotExist(err) {
-					m.Err = fmt.Errorf("Pfad existiert nicht: %v", m.Path)
-					return m, nil
+				for _, dir := range m.sources {
+					if _, err := os.Stat(dir); os.IsNotExist(err) {
+						m.Err = fmt.Errorf("Pfad existiert nicht: %v", dir)
+						return m, nil
+					}
 				}
 
-				m.State = stateScanning
+				m.State = stateOptions
 				m.Err = nil
-				return m, tea.Batch(m.Spinner.Tick, scanFiles(m.Path))
+				return m, nil
 
 			case tea.KeyEsc:
 				return m, func() tea.Msg { return BackMsg{} }
@@ -41,20 +46,209 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				return m, cmd
 			}
 
-		case statePreview:
+		case stateOptions:
+			if m.editingOption {
+				switch msg.Type {
+				case tea.KeyEnter:
+					*m.optionItems()[m.optionCursor].text(&m.Options) = m.optionInput.Value()
+					m.editingOption = false
+					m.optionInput.Blur()
+					m.Err = m.Options.validate()
+					return m, nil
+				case tea.KeyEsc:
+					m.editingOption = false
+					m.optionInput.Blur()
+					return m, nil
+				default:
+					m.optionInput, cmd = m.optionInput.Update(msg)
+					return m, cmd
+				}
+			}
+
 			switch msg.String() {
+			case "up", "k":
+				if m.optionCursor > 0 {
+					m.optionCursor--
+				}
+			case "down", "j":
+				if m.optionCursor < len(m.optionItems())-1 {
+					m.optionCursor++
+				}
+			case "left", "h":
+				m.optionItems()[m.optionCursor].step(&m.Options, -1)
+				m.Err = m.Options.validate()
+			case "right", "l", " ":
+				m.optionItems()[m.optionCursor].step(&m.Options, 1)
+				m.Err = m.Options.validate()
+			case "e":
+				item := m.optionItems()[m.optionCursor]
+				if item.text == nil {
+					return m, nil
+				}
+				m.editingOption = true
+				m.optionInput.SetValue(*item.text(&m.Options))
+				m.optionInput.CursorEnd()
+				return m, m.optionInput.Focus()
 			case "enter":
-				m.State = stateOrganizing
-				return m, tea.Batch(m.Spinner.Tick, organizeFiles(m.Path))
+				if err := m.Options.validate(); err != nil {
+					m.Err = err
+					return m, nil
+				}
+				m.Err = nil
+				if m.watchMode {
+					m.State = stateWatching
+					m.watchLog, m.watchMoved = nil, 0
+					var wait tea.Cmd
+					m.watch, wait = startWatch(m.sources, m.Options)
+					return m, wait
+				}
+				m.State = stateScanning
+				return m, tea.Batch(m.Spinner.Tick, scanFiles(m.sources, m.Options))
 			case "esc":
+				if m.watchMode {
+					m.State = stateWatchInput
+					return m, nil
+				}
+				m.State = stateInput
+				return m, nil
+			}
+
+		case statePreview:
+			return m.updatePreview(msg)
+
+		case stateOrganizing:
+			if msg.String() == "esc" && m.run != nil && !m.aborting {
+				m.aborting = true
+				m.run.cancel()
+			}
+			return m, nil
+
+		case stateAborted:
+			switch msg.String() {
+			case "r":
+				if m.journal == nil {
+					return m, nil
+				}
+				m.State = stateReverting
+				return m, tea.Batch(m.Spinner.Tick, revertRun(*m.journal))
+			case "b", "enter", "esc":
+				m.State = stateFinished
+				return m, nil
+			}
+
+		case stateWatchInput:
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.Path = m.TextInput.Value()
+				m.sources = splitFolders(m.Path)
+				if len(m.sources) == 0 {
+					m.Err = fmt.Errorf("Bitte gib mindestens einen Ordner ein.")
+					return m, nil
+				}
+				for _, dir := range m.sources {
+					if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+						m.Err = fmt.Errorf("Kein Ordner: %v", dir)
+						return m, nil
+					}
+				}
+				m.State = stateOptions
+				m.Err = nil
+				return m, nil
+
+			case tea.KeyEsc:
 				return m, func() tea.Msg { return BackMsg{} }
+
+			default:
+				m.TextInput, cmd = m.TextInput.Update(msg)
+				return m, cmd
+			}
+
+		case stateWatching:
+			if msg.String() == "esc" && m.watch != nil {
+				m.watch.cancel()
+			}
+			return m, nil
+
+		case stateCleanup:
+			switch msg.String() {
+			case "enter", "j", "y":
+				return m, cleanupDirs(m.journal, m.emptyDirs)
+			case "esc", "n":
+				m.emptyDirs = nil
+				m.State = stateFinished
+				return m, nil
 			}
 
-		case stateFinished:
+		case stateFinished, stateReverted:
 			if msg.String() == "enter" || msg.String() == "esc" {
 				return m, func() tea.Msg { return BackMsg{} }
 			}
+
+		case stateHistory:
+			switch msg.String() {
+			case "up", "k":
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			case "down", "j":
+				if m.cursor < len(m.journals)-1 {
+					m.cursor++
+				}
+			case "enter":
+				if len(m.journals) == 0 {
+					return m, func() tea.Msg { return BackMsg{} }
+				}
+				journal := m.journals[m.cursor]
+				if journal.Reverted != nil {
+					m.Err = fmt.Errorf("Dieser Lauf wurde bereits rückgängig gemacht.")
+					return m, nil
+				}
+				m.Err = nil
+				m.State = stateReverting
+				return m, tea.Batch(m.Spinner.Tick, revertRun(journal))
+			case "esc":
+				return m, func() tea.Msg { return BackMsg{} }
+			}
+		}
+
+	case WatchEventMsg:
+		m.watchLog = append(m.watchLog, msg.Event)
+		if len(m.watchLog) > watchLogLimit {
+			m.watchLog = m.watchLog[len(m.watchLog)-watchLogLimit:]
+		}
+		if msg.Event.Dest != "" {
+			m.watchMoved++
+		}
+		return m, m.watch.wait()
+
+	case WatchStoppedMsg:
+		m.watch = nil
+		if msg.Err != nil {
+			m.Err = fmt.Errorf("Überwachung fehlgeschlagen: %w", msg.Err)
+			m.State = stateFinished
+			return m, nil
+		}
+		return m, func() tea.Msg { return BackMsg{} }
+
+	case HistoryLoadedMsg:
+		if msg.Err != nil {
+			m.Err = fmt.Errorf("Verlauf konnte nicht geladen werden: %w", msg.Err)
+			m.State = stateFinished
+			return m, nil
+		}
+		m.journals = msg.Journals
+		m.cursor = 0
+		return m, nil
+
+	case RevertCompleteMsg:
+		if msg.Err != nil {
+			m.Err = fmt.Errorf("Fehler beim Rückgängigmachen: %w", msg.Err)
+			m.State = stateFinished
+			return m, nil
 		}
+		m.revert = msg.Result
+		m.State = stateReverted
+		return m, nil
 
 	case ScanCompleteMsg:
 		if msg.Err != nil {
@@ -62,20 +256,99 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.State = stateFinished
 			return m, nil
 		}
+		if m.planPath != "" {
+			os.Remove(m.planPath)
+			m.planPath = ""
+		}
 		m.files = msg.Files
 		m.totalFiles = msg.TotalFiles
+		m.ignored = msg.Ignored
+		m.busy = msg.Busy
+		m.dangling = msg.Dangling
+		m.excludedExts = make(map[string]bool)
+		m.filterInput.SetValue("")
+		m.refreshTable()
 		m.State = statePreview
 		return m, nil
 
+	case PlanEditedMsg:
+		if msg.Err != nil {
+			m.Err = fmt.Errorf("Editor: %w", msg.Err)
+			m.planPath = msg.Path
+			return m, nil
+		}
+		data, err := os.ReadFile(msg.Path)
+		if err != nil {
+			m.Err = err
+			return m, nil
+		}
+		files, err := applyPlan(m.files, string(data), m.Options)
+		if err != nil {
+			// Keep the file, so the next edit can fix it.
+			m.Err = err
+			m.planPath = msg.Path
+			return m, nil
+		}
+		os.Remove(msg.Path)
+		m.planPath = ""
+		m.files = files
+		m.Err = nil
+		m.refreshTable()
+		return m, nil
+
+	case OrganizeProgressMsg:
+		m.progress = msg.Current
+		m.total = msg.Total
+		m.currentFile = msg.File
+		m.bytesMoved = msg.BytesMoved
+		m.bytesTotal = msg.BytesTotal
+		return m, m.run.wait()
+
 	case OrganizeCompleteMsg:
+		m.run = nil
+		m.stats = msg.Stats
+		m.journal = msg.Journal
 		if msg.Err != nil {
 			m.Err = fmt.Errorf("Fehler beim Organisieren: %w", msg.Err)
-		} else {
-			m.stats = msg.Stats
+		}
+		if (msg.Aborted || msg.Err != nil) && msg.Journal != nil {
+			// Let the user decide whether the partial run stays.
+			m.State = stateAborted
+			return m, nil
+		}
+		if msg.Aborted || msg.Err != nil || m.Options.linking() {
+			m.State = stateFinished
+			return m, nil
+		}
+		// Offer to remove folders the run left empty before showing the
+		// result.
+		return m, scanEmptyDirs(m.sources, m.Options)
+
+	case EmptyDirsMsg:
+		if msg.Err != nil || len(msg.Dirs) == 0 {
+			m.State = stateFinished
+			return m, nil
+		}
+		m.emptyDirs = msg.Dirs
+		m.State = stateCleanup
+		return m, nil
+
+	case CleanupCompleteMsg:
+		m.stats.RemovedDirs = msg.Removed
+		if msg.Err != nil {
+			m.Err = fmt.Errorf("Fehler beim Aufräumen: %w", msg.Err)
 		}
 		m.State = stateFinished
 		return m, nil
 
+	case tea.WindowSizeMsg:
+		m.progressBar.Width = msg.Width - 4
+		if m.progressBar.Width > 80 {
+			m.progressBar.Width = 80
+		}
+		m.table.SetColumns(previewColumns(msg.Width-4, m.Options.Flatten))
+		return m, nil
+
 	case spinner.TickMsg:
 		m.Spinner, cmd = m.Spinner.Update(msg)
 		return m, cmd
@@ -83,3 +356,15 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 	return m, cmd
 }
+
+// splitFolders reads the folders typed into the input, separated by ":" (";"
+// on Windows); blanks around them and empty entries are dropped.
+func splitFolders(input string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(input) {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,27 @@
+package organizer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFolders(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"a", []string{"a"}},
+		{"a" + sep + " b", []string{"a", "b"}},
+		{" a " + sep + sep + "b" + sep, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitFolders(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitFolders(%q) = %q, erwartet %q", strings.ReplaceAll(tt.input, sep, "|"), got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,14 @@ package organizer
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"example/ordi/internal/ignore"
+	"example/ordi/internal/inuse"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -33,6 +40,9 @@ var (
 		Foreground(lipgloss.Color("63")).
 		Bold(true)
 
+	barStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("212"))
+
 	groupStyle = lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("63")).
@@ -41,6 +51,9 @@ var (
 )
 
 func (m Model) Init() tea.Cmd {
+	if m.State == stateHistory {
+		return loadHistory
+	}
 	return nil
 }
 
@@ -49,16 +62,127 @@ func (m Model) View() string {
 
 	switch m.State {
 	case stateInput:
-		b.WriteString(titleStyle.Render("📂 Verzeichnis organisieren"))
-		b.WriteString("\n\n")
+		if m.Options.Flatten {
+			b.WriteString(titleStyle.Render("🗂️  Unterordner auflösen"))
+			b.WriteString("\n\n")
+			b.WriteString("Alle Dateien aus den Unterordnern werden in einen Ordner verschoben.\n")
+		} else {
+			b.WriteString(titleStyle.Render("📂 Verzeichnis organisieren"))
+			b.WriteString("\n\n")
+		}
 		b.WriteString("Geben Sie den Pfad zum Ordner ein:\n\n")
 		b.WriteString(m.TextInput.View())
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render(fmt.Sprintf("Mehrere Quellordner mit %q trennen; der Zielordner wird in den Optionen gewählt.", string(os.PathListSeparator))))
+		b.WriteString("\n\n")
+		if m.configErr != nil {
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  Kategorien-Konfiguration fehlerhaft, Standardkategorien werden verwendet: %v", m.configErr)))
+			b.WriteString("\n\n")
+		}
+		if m.learnErr != nil {
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  Gelernte Kategorien konnten nicht geladen werden: %v", m.learnErr)))
+			b.WriteString("\n\n")
+		}
+		if m.Err != nil {
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %v", m.Err)))
+			b.WriteString("\n\n")
+		}
+		b.WriteString(helpStyle.Render("Enter = Weiter • Esc = Zurück zum Menü"))
+
+	case stateWatchInput:
+		b.WriteString(titleStyle.Render("👀 Ordner überwachen"))
+		b.WriteString("\n\n")
+		b.WriteString("Neue Dateien in diesen Ordnern werden automatisch einsortiert.\n")
+		b.WriteString("Geben Sie die Pfade zu den Ordnern ein:\n\n")
+		b.WriteString(m.TextInput.View())
 		b.WriteString("\n\n")
+		if m.configErr != nil {
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  Kategorien-Konfiguration fehlerhaft, Standardkategorien werden verwendet: %v", m.configErr)))
+			b.WriteString("\n\n")
+		}
 		if m.Err != nil {
 			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %v", m.Err)))
 			b.WriteString("\n\n")
 		}
-		b.WriteString(helpStyle.Render("Enter = Scannen • Esc = Zurück zum Menü"))
+		b.WriteString(helpStyle.Render("Enter = Weiter • Esc = Zurück zum Menü"))
+
+	case stateWatching:
+		b.WriteString(titleStyle.Render("👀 Überwachung läuft"))
+		b.WriteString("\n\n")
+		b.WriteString(infoStyle.Render(fmt.Sprintf("%d Dateien einsortiert", m.watchMoved)))
+		b.WriteString("\n\n")
+
+		if len(m.watchLog) == 0 {
+			b.WriteString(helpStyle.Render("Warte auf neue Dateien..."))
+			b.WriteString("\n")
+		}
+		start := len(m.watchLog) - watchLogVisible
+		if start < 0 {
+			start = 0
+		}
+		for _, event := range m.watchLog[start:] {
+			line := truncate(event.String(), 100)
+			switch {
+			case event.Err != nil:
+				b.WriteString(errorStyle.Render(line))
+			case event.Dest != "":
+				b.WriteString(line)
+			default:
+				b.WriteString(helpStyle.UnsetMarginTop().Render(line))
+			}
+			b.WriteString("\n")
+		}
+
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Esc = Überwachung beenden"))
+
+	case stateOptions:
+		b.WriteString(titleStyle.Render("⚙️  Optionen"))
+		b.WriteString("\n\n")
+		if len(m.sources) > 1 {
+			b.WriteString(fmt.Sprintf("Quellordner: %s\n\n", strings.Join(m.sources, ", ")))
+		} else {
+			b.WriteString(fmt.Sprintf("Verzeichnis: %s\n\n", m.Path))
+		}
+
+		for i, item := range m.optionItems() {
+			cursor := "  "
+			style := lipgloss.NewStyle()
+			if i == m.optionCursor {
+				cursor = "> "
+				style = style.Foreground(lipgloss.Color("205"))
+			}
+			value := style.Render("‹ " + item.value(m.Options) + " ›")
+			if m.editingOption && i == m.optionCursor {
+				value = m.optionInput.View()
+			}
+			b.WriteString(fmt.Sprintf("%s%-24s %s\n", cursor, item.label+":", value))
+		}
+
+		if m.optionItems()[m.optionCursor].placeholders {
+			var placeholders []string
+			for _, p := range templatePlaceholders {
+				placeholders = append(placeholders, fmt.Sprintf("  {%s} – %s", p.name, p.desc))
+			}
+			b.WriteString("\n")
+			b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, append([]string{"Platzhalter:"}, placeholders...)...)))
+			b.WriteString("\n")
+		}
+
+		if m.Err != nil {
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %v", m.Err)))
+			b.WriteString("\n")
+		}
+
+		b.WriteString("\n")
+		if m.editingOption {
+			b.WriteString(helpStyle.Render("Enter = Übernehmen • Esc = Verwerfen"))
+		} else if m.watchMode {
+			b.WriteString(helpStyle.Render("↑/↓ = Navigieren • ←/→ = Ändern • e = Bearbeiten • Enter = Überwachung starten • Esc = Zurück"))
+		} else {
+			b.WriteString(helpStyle.Render("↑/↓ = Navigieren • ←/→ = Ändern • e = Bearbeiten • Enter = Scannen • Esc = Zurück"))
+		}
 
 	case stateScanning:
 		b.WriteString(titleStyle.Render("🔍 Scanne Dateien..."))
@@ -71,56 +195,290 @@ func (m Model) View() string {
 		b.WriteString(titleStyle.Render("📋 Vorschau"))
 		b.WriteString("\n\n")
 
-		// Count files per category
-		categoryCount := make(map[string]int)
+		excluded, archived, suggested := 0, 0, 0
+		var archivedBytes int64
+		bundles := make(map[string]bool)
 		for _, file := range m.files {
-			categoryCount[file.Category]++
+			if file.excluded() {
+				excluded++
+				continue
+			}
+			if file.Action == actionArchive {
+				archived++
+				archivedBytes += file.Size
+				bundles[file.Dest] = true
+				continue
+			}
+			if file.Suggested > 0 {
+				suggested++
+			}
 		}
 
-		b.WriteString(infoStyle.Render(fmt.Sprintf("Gefundene Dateien: %d\n", m.totalFiles)))
+		found := fmt.Sprintf("Gefundene Dateien: %d", m.totalFiles)
+		if excluded > 0 {
+			found += fmt.Sprintf(", davon %d ausgeschlossen", excluded)
+		}
+		if m.ignored > 0 {
+			found += fmt.Sprintf(" • %d Einträge ignoriert (%s)", m.ignored, ignore.FileName)
+		}
+		b.WriteString(infoStyle.Render(found + "\n"))
+		if len(m.busy) > 0 {
+			b.WriteString(m.busyList(m.busy))
+			b.WriteString("\n")
+		}
+		if len(m.sources) > 1 {
+			perSource := make(map[string]int)
+			for _, file := range m.files {
+				if !file.excluded() {
+					perSource[file.Source]++
+				}
+			}
+			lines := []string{"Quellen:"}
+			for _, source := range m.sources {
+				abs, _ := filepath.Abs(source)
+				lines = append(lines, fmt.Sprintf("  %s: %d Dateien", source, perSource[abs]))
+			}
+			b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+			b.WriteString("\n")
+		}
+		if target, _ := m.Options.targetRoot(m.sources[0]); strings.TrimSpace(m.Options.Target) != "" && !m.Options.linking() {
+			b.WriteString(infoStyle.Render("Ziel: " + target))
+			b.WriteString("\n")
+		}
+		if m.Options.linking() {
+			target, _ := m.Options.linkRoot()
+			link := fmt.Sprintf("%s in %s", m.Options.Mode, target)
+			if len(m.dangling) > 0 {
+				link += fmt.Sprintf(" • %d verwaiste Links werden entfernt", len(m.dangling))
+			}
+			b.WriteString(infoStyle.Render(link))
+			b.WriteString("\n")
+		}
+		if suggested > 0 {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("🧠 %d Dateien nach früheren Korrekturen zugeordnet (Hinweis „gelernt“)", suggested)))
+			b.WriteString("\n")
+		}
+		if archived > 0 {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("📦 %d Dateien (%s) werden archiviert • %d Archivdatei(en) im Format %s", archived, formatBytes(archivedBytes), len(bundles), m.Options.ArchiveFormat)))
+			b.WriteString("\n")
+		}
+		if len(m.excludedExts) > 0 {
+			var exts []string
+			for _, ext := range sortedKeys(m.excludedExts) {
+				exts = append(exts, extLabel(ext))
+			}
+			b.WriteString(infoStyle.Render("Ausgeschlossene Endungen: " + strings.Join(exts, ", ")))
+			b.WriteString("\n")
+		}
 		b.WriteString("\n")
 
-		// Show category breakdown in fixed order
-		stats := []string{"Kategorien:"}
-		categoryOrder := []string{"Bilder", "Videos", "Musik", "Dokumente", "Archive", "Sonstiges"}
-		for _, category := range categoryOrder {
-			if count, exists := categoryCount[category]; exists {
-				icon := getCategoryIcon(category)
-				stats = append(stats, fmt.Sprintf("  %s %-12s %d Dateien", icon, category+":", count))
+		if m.Options.Flatten {
+			b.WriteString(categoryStyle.Render(flattenSummary(m.files)))
+		} else {
+			// Show category breakdown in taxonomy order
+			stats := planStats(m.files, m.Options, time.Now())
+			lines := append([]string{"Kategorien:"}, m.sizeLines(stats)...)
+			b.WriteString(categoryStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+			if m.showStats {
+				b.WriteString("\n\n")
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.largestLines(stats)...)))
+				b.WriteString("\n\n")
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, ageLines(stats)...)))
 			}
 		}
-		b.WriteString(categoryStyle.Render(lipgloss.JoinVertical(lipgloss.Left, stats...)))
 		b.WriteString("\n\n")
 
-		// Show first 10 files
-		if len(m.files) > 0 {
-			maxShow := 10
-			if len(m.files) < maxShow {
-				maxShow = len(m.files)
+		if m.Options.PlaceRadius > 0 {
+			names, counts := photoPlaces(m.files, m.Options)
+			lines := []string{fmt.Sprintf("Orte (%s):", placeRadiusLabel(m.Options.PlaceRadius))}
+			for i, name := range names {
+				if i >= 10 {
+					lines = append(lines, fmt.Sprintf("  ... und %d weitere", len(names)-i))
+					break
+				}
+				lines = append(lines, fmt.Sprintf("  📍 %-40s %d Fotos", truncate(name, 40), counts[name]))
 			}
+			if len(names) > 0 {
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+				b.WriteString("\n\n")
+			}
+		}
 
-			var samples []string
-			for i := 0; i < maxShow; i++ {
-				file := m.files[i]
-				samples = append(samples, fmt.Sprintf("  %s %s → %s", file.Icon, truncate(file.Name, 40), file.Category))
+		if events := photoEvents(m.files, m.Options); len(events) > 0 {
+			lines := []string{fmt.Sprintf("Ereignisse (%s):", eventGapLabel(m.Options.EventGap))}
+			for i, event := range events {
+				if i >= 10 {
+					lines = append(lines, fmt.Sprintf("  ... und %d weitere", len(events)-i))
+					break
+				}
+				lines = append(lines, fmt.Sprintf("  📅 %-40s %d Fotos", truncate(m.Options.eventName(event.Key), 40), len(event.Files)))
 			}
+			b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+			b.WriteString("\n\n")
+		}
 
-			if len(m.files) > maxShow {
-				samples = append(samples, fmt.Sprintf("  ... und %d weitere", len(m.files)-maxShow))
+		if m.Options.Recursive {
+			depthCount := make(map[int]int)
+			maxDepth := 0
+			for _, file := range m.files {
+				depthCount[file.Depth]++
+				if file.Depth > maxDepth {
+					maxDepth = file.Depth
+				}
+			}
+			depths := []string{"Herkunft nach Ordnertiefe:"}
+			for depth := 0; depth <= maxDepth; depth++ {
+				if count, exists := depthCount[depth]; exists {
+					label := fmt.Sprintf("Ebene %d", depth)
+					if depth == 0 {
+						label = "Hauptordner"
+					}
+					depths = append(depths, fmt.Sprintf("  %-12s %d Dateien", label+":", count))
+				}
 			}
+			b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, depths...)))
+			b.WriteString("\n\n")
+		}
 
-			b.WriteString(groupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, samples...)))
+		b.WriteString(m.table.View())
+		b.WriteString("\n")
+		if m.filtering || m.filterInput.Value() != "" {
+			b.WriteString(m.filterInput.View())
+			b.WriteString(fmt.Sprintf("  (%d von %d Dateien)\n", len(m.visible), len(m.files)))
+		}
+
+		mismatches, conflicts := 0, 0
+		for _, file := range m.files {
+			if file.excluded() {
+				continue
+			}
+			if file.Mismatch {
+				mismatches++
+			}
+			if file.Action != actionMove && file.Action != actionLinked && file.Action != actionArchive {
+				conflicts++
+			}
+		}
+		if clashes := countClashes(approvedPlan(m.files)); clashes > 0 {
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %d Dateien heißen wie eine Datei aus einer anderen Quelle und folgen der Konfliktregel – r = beide behalten, R = alle behalten, x = ausschließen", clashes)))
+		}
+		if mismatches > 0 {
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %d Dateien mit unpassender Endung, einsortiert nach Inhalt, sofern kein Namensmuster passt", mismatches)))
+		}
+		if conflicts > 0 {
+			resolution := m.Options.Conflict.String()
+			if m.Options.Flatten {
+				resolution = m.Options.FlattenNaming.String()
+			}
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %d Namenskonflikte (%s)", conflicts, resolution)))
+		}
+		if m.Err != nil {
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %v", m.Err)))
 		}
 
 		b.WriteString("\n")
-		b.WriteString(helpStyle.Render("Enter = Organisieren starten • Esc = Abbrechen"))
+		if m.ignoring {
+			target := "in " + ignore.FileName + " dieses Ordners"
+			if m.ignoreGlobal {
+				target = "global"
+			}
+			b.WriteString("\n")
+			b.WriteString(fmt.Sprintf("Ignorier-Muster (%s):\n", target))
+			b.WriteString(m.ignoreInput.View())
+			b.WriteString("\n")
+			b.WriteString(helpStyle.Render("Enter = Speichern und neu scannen • Tab = Ordner/global • Esc = Abbrechen"))
+			break
+		}
+		if m.namingEvent != "" {
+			b.WriteString("\n")
+			b.WriteString(fmt.Sprintf("Name für das Ereignis %s:\n", m.namingEvent))
+			b.WriteString(m.eventInput.View())
+			b.WriteString("\n")
+			b.WriteString(helpStyle.Render("Enter = Übernehmen (leer = Datum) • Esc = Abbrechen"))
+			break
+		}
+		if m.filtering {
+			b.WriteString(helpStyle.Render("Enter = Filter übernehmen • Esc = Filter löschen"))
+			break
+		}
+		if m.Options.Flatten {
+			b.WriteString(helpStyle.Render("↑/↓ = Navigieren • x = Datei ausschließen • X = Endung ausschließen • i = Ignorieren • / = Filtern"))
+			b.WriteString("\n")
+			b.WriteString(helpStyle.Render("e = Im Editor bearbeiten • Enter = Zusammenführen starten • Esc = Abbrechen"))
+			break
+		}
+		b.WriteString(helpStyle.Render("↑/↓ = Navigieren • ←/→ = Kategorie ändern • x = Datei ausschließen • X = Endung ausschließen • i = Ignorieren • / = Filtern • s = Statistik"))
+		b.WriteString("\n")
+		if m.Options.EventGap > 0 {
+			b.WriteString(helpStyle.Render("n = Ereignis umbenennen • +/- = Pause zwischen Ereignissen ändern"))
+			b.WriteString("\n")
+		}
+		b.WriteString(helpStyle.Render("e = Im Editor bearbeiten • Enter = Organisieren starten • Esc = Abbrechen"))
 
 	case stateOrganizing:
 		b.WriteString(titleStyle.Render("📦 Organisiere Dateien..."))
 		b.WriteString("\n\n")
-		b.WriteString(fmt.Sprintf("%s Verschiebe Dateien in Kategorien...\n", m.Spinner.View()))
+		percent := 0.0
+		if m.total > 0 {
+			percent = float64(m.progress) / float64(m.total)
+		}
+		b.WriteString(m.progressBar.ViewAs(percent))
+		b.WriteString(fmt.Sprintf("\n\n%d / %d Dateien • %s von %s verschoben\n", m.progress, m.total, formatBytes(m.bytesMoved), formatBytes(m.bytesTotal)))
+		if m.currentFile != "" {
+			b.WriteString(fmt.Sprintf("%s %s\n", m.Spinner.View(), truncate(m.currentFile, 70)))
+		}
 		b.WriteString("\n")
-		b.WriteString(helpStyle.Render("Bitte warten..."))
+		if m.aborting {
+			b.WriteString(helpStyle.Render("Breche nach der aktuellen Datei ab..."))
+		} else {
+			b.WriteString(helpStyle.Render("Esc = Abbrechen"))
+		}
+
+	case stateAborted:
+		if m.Err != nil {
+			b.WriteString(titleStyle.Render("❌ Organisation unterbrochen"))
+			b.WriteString("\n\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("Fehler: %v", m.Err)))
+		} else {
+			b.WriteString(titleStyle.Render("⏹️  Organisation abgebrochen"))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(infoStyle.Render(fmt.Sprintf("%d von %d Dateien wurden bereits verschoben.", m.stats.TotalMoved, m.total)))
+		b.WriteString("\n\n")
+		b.WriteString("Was soll mit den bereits verschobenen Dateien passieren?\n")
+		b.WriteString(helpStyle.Render("b/Enter = Behalten • r = Zurückrollen"))
+
+	case stateCleanup:
+		b.WriteString(titleStyle.Render("🧹 Leere Ordner entfernen?"))
+		b.WriteString("\n\n")
+		b.WriteString(successStyle.Render(fmt.Sprintf("✓ %d Dateien organisiert", m.stats.TotalMoved)))
+		b.WriteString("\n\n")
+
+		top := topEmptyDirs(m.emptyDirs)
+		b.WriteString("Diese Ordner sind jetzt leer oder enthalten nur Systemdateien (Thumbs.db, .DS_Store, desktop.ini):\n\n")
+		var lines []string
+		for i, dir := range top {
+			if i >= 15 {
+				lines = append(lines, fmt.Sprintf("  ... und %d weitere", len(top)-i))
+				break
+			}
+			line := "  " + truncate(m.displayPath(dir.Path), 70) + string(filepath.Separator)
+			if len(dir.Junk) > 0 {
+				line += fmt.Sprintf("  (%d Systemdateien)", len(dir.Junk))
+			}
+			lines = append(lines, line)
+		}
+		b.WriteString(groupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+		b.WriteString("\n")
+		if nested := len(m.emptyDirs) - len(top); nested > 0 {
+			b.WriteString(infoStyle.Render(fmt.Sprintf("Insgesamt %d Ordner inklusive Unterordnern.", len(m.emptyDirs))))
+			b.WriteString("\n")
+		}
+		b.WriteString(helpStyle.Render("Enter/j = Entfernen • Esc/n = Behalten"))
 
 	case stateFinished:
 		if m.Err != nil {
@@ -128,30 +486,294 @@ func (m Model) View() string {
 			b.WriteString("\n\n")
 			b.WriteString(errorStyle.Render(fmt.Sprintf("Fehler: %v", m.Err)))
 		} else {
-			b.WriteString(titleStyle.Render("✅ Organisation abgeschlossen"))
+			if m.aborting {
+				b.WriteString(titleStyle.Render("⏹️  Organisation abgebrochen"))
+			} else {
+				b.WriteString(titleStyle.Render("✅ Organisation abgeschlossen"))
+			}
 			b.WriteString("\n\n")
-			b.WriteString(successStyle.Render(fmt.Sprintf("✓ %d Dateien erfolgreich organisiert!", m.stats.TotalMoved)))
+			if m.Options.linking() {
+				b.WriteString(successStyle.Render(fmt.Sprintf("✓ %d Links angelegt!", m.stats.TotalMoved)))
+				var links []string
+				if m.stats.UpToDate > 0 {
+					links = append(links, fmt.Sprintf("%d bereits verknüpft", m.stats.UpToDate))
+				}
+				if m.stats.PrunedLinks > 0 {
+					links = append(links, fmt.Sprintf("%d verwaiste Links entfernt", m.stats.PrunedLinks))
+				}
+				if len(links) > 0 {
+					b.WriteString("\n")
+					b.WriteString(infoStyle.Render(strings.Join(links, " • ")))
+				}
+			} else if m.Options.Flatten {
+				b.WriteString(successStyle.Render(fmt.Sprintf("✓ %d Dateien zusammengeführt!", m.stats.TotalMoved)))
+			} else {
+				b.WriteString(successStyle.Render(fmt.Sprintf("✓ %d Dateien erfolgreich organisiert!", m.stats.TotalMoved)))
+			}
 			b.WriteString("\n\n")
 
-			if len(m.stats.Categories) > 0 {
-				stats := []string{"Dateien pro Kategorie:"}
-				categoryOrder := []string{"Bilder", "Videos", "Musik", "Dokumente", "Archive", "Sonstiges"}
-				for _, category := range categoryOrder {
-					if count, exists := m.stats.Categories[category]; exists {
-						icon := getCategoryIcon(category)
-						stats = append(stats, fmt.Sprintf("  %s %-12s %d Dateien", icon, category+":", count))
-					}
+			var conflicts []string
+			if m.stats.Renamed > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("  %d umbenannt", m.stats.Renamed))
+			}
+			if m.stats.Overwritten > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("  %d überschrieben", m.stats.Overwritten))
+			}
+			if m.stats.Duplicates > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("  %d identische Dateien entfernt", m.stats.Duplicates))
+			}
+			if m.stats.Skipped > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("  %d übersprungen", m.stats.Skipped))
+			}
+			if len(conflicts) > 0 {
+				conflicts = append([]string{"Namenskonflikte:"}, conflicts...)
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, conflicts...)))
+				b.WriteString("\n\n")
+			}
+
+			if len(m.stats.Busy) > 0 {
+				b.WriteString(m.busyList(m.stats.Busy))
+				b.WriteString("\n\n")
+			}
+
+			if m.stats.Archived > 0 {
+				b.WriteString(infoStyle.Render(fmt.Sprintf("📦 %d Dateien (%s) archiviert • %d Archivdatei(en) in %s", m.stats.Archived, formatBytes(m.stats.ArchivedBytes), len(m.stats.Archives), archiveDirName)))
+				b.WriteString("\n\n")
+			}
+
+			if m.stats.Learned > 0 && m.stats.LearnErr == nil {
+				b.WriteString(infoStyle.Render(fmt.Sprintf("🧠 %d Korrekturen für künftige Vorschläge gelernt", m.stats.Learned)))
+				b.WriteString("\n\n")
+			}
+			if m.stats.LearnErr != nil {
+				b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  Korrekturen konnten nicht gespeichert werden: %v", m.stats.LearnErr)))
+				b.WriteString("\n\n")
+			}
+
+			if m.stats.RemovedDirs > 0 {
+				b.WriteString(infoStyle.Render(fmt.Sprintf("🧹 %d leere Ordner entfernt", m.stats.RemovedDirs)))
+				b.WriteString("\n\n")
+			}
+
+			if len(m.stats.files) > 0 && !m.Options.Flatten {
+				stats := planStats(m.stats.files, m.Options, time.Now())
+				lines := append([]string{"Dateien pro Kategorie:"}, m.sizeLines(stats)...)
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+				b.WriteString("\n\n")
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.largestLines(stats)...)))
+				b.WriteString("\n\n")
+				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, ageLines(stats)...)))
+			}
+			b.WriteString("\n\n")
+			b.WriteString(helpStyle.Render("Über \"Rückgängig machen\" im Menü lässt sich dieser Lauf zurücksetzen."))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("Enter = Zurück zum Menü"))
+
+	case stateHistory:
+		b.WriteString(titleStyle.Render("↩️  Rückgängig machen"))
+		b.WriteString("\n\n")
+
+		if len(m.journals) == 0 {
+			b.WriteString(infoStyle.Render("Es wurden noch keine Läufe aufgezeichnet."))
+			b.WriteString("\n\n")
+			b.WriteString(helpStyle.Render("Enter/Esc = Zurück zum Menü"))
+			break
+		}
+
+		b.WriteString("Wähle den Lauf, der rückgängig gemacht werden soll:\n\n")
+		for i, journal := range m.journals {
+			cursor := "  "
+			style := lipgloss.NewStyle()
+			if i == m.cursor {
+				cursor = "> "
+				style = style.Foreground(lipgloss.Color("205"))
+			}
+			line := fmt.Sprintf("%s  %-50s %4d Dateien",
+				journal.Started.Format("02.01.2006 15:04"), truncate(journal.Root, 50), len(journal.Moves))
+			if journal.Reverted != nil {
+				line += "  (bereits rückgängig gemacht)"
+				if i != m.cursor {
+					style = style.Foreground(lipgloss.Color("241"))
 				}
-				b.WriteString(infoStyle.Render(lipgloss.JoinVertical(lipgloss.Left, stats...)))
+			} else if journal.partlyReverted() {
+				line += "  (teilweise rückgängig gemacht)"
 			}
+			b.WriteString(cursor + style.Render(line) + "\n")
+		}
+
+		if m.Err != nil {
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("⚠️  %v", m.Err)))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("↑/↓ = Navigieren • Enter = Rückgängig machen • Esc = Zurück zum Menü"))
+
+	case stateReverting:
+		b.WriteString(titleStyle.Render("↩️  Mache Lauf rückgängig..."))
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("%s Verschiebe Dateien zurück...\n", m.Spinner.View()))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Bitte warten..."))
+
+	case stateReverted:
+		if m.revert.Partial {
+			b.WriteString(titleStyle.Render("⚠️  Lauf teilweise rückgängig gemacht"))
+		} else {
+			b.WriteString(titleStyle.Render("✅ Lauf rückgängig gemacht"))
 		}
 		b.WriteString("\n\n")
+		b.WriteString(successStyle.Render(fmt.Sprintf("✓ %d Dateien wiederhergestellt", m.revert.Restored)))
+		b.WriteString("\n\n")
+
+		if len(m.revert.Problems) > 0 {
+			lines := []string{fmt.Sprintf("%d Dateien konnten nicht zurückgesetzt werden:", len(m.revert.Problems))}
+			for i, problem := range m.revert.Problems {
+				if i >= 10 {
+					lines = append(lines, fmt.Sprintf("  ... und %d weitere", len(m.revert.Problems)-i))
+					break
+				}
+				lines = append(lines, fmt.Sprintf("  • %s – %s", truncate(problem.Path, 60), problem.Reason))
+			}
+			b.WriteString(errorStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...)))
+			b.WriteString("\n\n")
+		}
+		if m.revert.Partial {
+			b.WriteString("Der Lauf bleibt im Verlauf und kann nach dem Beheben erneut rückgängig gemacht werden.\n\n")
+		}
 		b.WriteString(helpStyle.Render("Enter = Zurück zum Menü"))
 	}
 
 	return b.String()
 }
 
+// barWidth is the width of a full bar in the charts, in cells.
+const barWidth = 24
+
+// bar draws value as a horizontal bar relative to max, with eighth blocks
+// for the remainder.
+func bar(value, max int64) string {
+	if max <= 0 || value <= 0 {
+		return ""
+	}
+	eighths := int(value * barWidth * 8 / max)
+	if eighths == 0 {
+		eighths = 1
+	}
+	partial := []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+	return strings.Repeat("█", eighths/8) + partial[eighths%8]
+}
+
+// sizeLines renders the bytes per category as bar chart.
+func (m Model) sizeLines(s Stats) []string {
+	taxonomy := m.Options.taxonomy()
+	width := 12
+	var max int64
+	for _, usage := range s.Categories {
+		if len(usage.Category)+1 > width {
+			width = len(usage.Category) + 1
+		}
+		if usage.Bytes > max {
+			max = usage.Bytes
+		}
+	}
+
+	var lines []string
+	for _, usage := range s.Categories {
+		icon := taxonomy.getCategoryIcon(usage.Category)
+		lines = append(lines, fmt.Sprintf("  %s %-*s %5d Dateien %9s  %s",
+			icon, width, usage.Category+":", usage.Files, formatBytes(usage.Bytes), barStyle.Render(bar(usage.Bytes, max))))
+	}
+	return lines
+}
+
+// largestLines lists the biggest files, after a run where they are now.
+func (m Model) largestLines(s Stats) []string {
+	lines := []string{"Größte Dateien:"}
+	for _, file := range s.Largest {
+		path := file.Path
+		if m.State == stateFinished {
+			path = file.Dest
+		}
+		lines = append(lines, fmt.Sprintf("  %9s  %s", formatBytes(file.Bytes), truncate(m.displayPath(path), 60)))
+	}
+	return lines
+}
+
+// ageLines renders the age histogram by number of files.
+func ageLines(s Stats) []string {
+	var max int64
+	for _, age := range s.Ages {
+		if int64(age.Files) > max {
+			max = int64(age.Files)
+		}
+	}
+	lines := []string{"Alter (letzte Änderung):"}
+	for _, age := range s.Ages {
+		lines = append(lines, fmt.Sprintf("  %-11s %5d Dateien %9s  %s",
+			age.Label, age.Files, formatBytes(age.Bytes), barStyle.Render(bar(int64(age.Files), max))))
+	}
+	return lines
+}
+
+// busyList names the files that were left alone because they are in use.
+func (m Model) busyList(files []inuse.File) string {
+	lines := []string{fmt.Sprintf("⏳ %d Dateien in Benutzung, nicht angefasst:", len(files))}
+	for i, file := range files {
+		if i >= 5 {
+			lines = append(lines, fmt.Sprintf("  ... und %d weitere", len(files)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %s (%s)", truncate(m.displayPath(file.Path), 50), file.Reason))
+	}
+	return errorStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// displayPath shortens path relative to the target folder or, failing that,
+// the source folder it lies in.
+func (m Model) displayPath(path string) string {
+	var roots []string
+	if len(m.sources) > 0 {
+		if target, err := m.Options.targetRoot(m.sources[0]); err == nil {
+			roots = append(roots, target)
+		}
+	}
+	for _, source := range m.sources {
+		if root, err := filepath.Abs(source); err == nil {
+			roots = append(roots, root)
+		}
+	}
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
+	}
+	return path
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
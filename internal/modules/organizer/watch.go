@@ -0,0 +1,281 @@
+package organizer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example/ordi/internal/ignore"
+	"example/ordi/internal/inuse"
+	"example/ordi/internal/sidecar"
+)
+
+// WatchEvent is one entry of the watch log.
+type WatchEvent struct {
+	Time     time.Time
+	Path     string
+	Dest     string
+	Category string
+	Message  string
+	Err      error
+}
+
+func (e WatchEvent) String() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s  Fehler: %v", e.Time.Format("15:04:05"), e.Err)
+	case e.Dest != "":
+		return fmt.Sprintf("%s  %s → %s", e.Time.Format("15:04:05"), e.Path, e.Dest)
+	default:
+		return fmt.Sprintf("%s  %s", e.Time.Format("15:04:05"), e.Message)
+	}
+}
+
+const (
+	// watchSettle is how long a file must keep its size and modification
+	// time before it is considered complete.
+	watchSettle = 3 * time.Second
+	watchTick   = 500 * time.Millisecond
+
+	// watchLogLimit is the number of log entries kept by the TUI, of which
+	// the last watchLogVisible are shown.
+	watchLogLimit   = 200
+	watchLogVisible = 15
+)
+
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+	busy    bool
+}
+
+// Watch organizes files that appear directly in one of dirs until ctx is
+// cancelled. Every organized file and every problem is sent to events. All
+// moves of one folder during a session share a journal, so a session can be
+// reverted like a normal run.
+func Watch(ctx context.Context, dirs []string, opts Options, events chan<- WatchEvent) error {
+	// New arrivals are sorted; packing old files and grouping photos into
+	// events or places is left to a full run.
+	opts.ArchiveAfter = 0
+	opts.EventGap = 0
+	opts.PlaceRadius = 0
+
+	tmpl, err := opts.template()
+	if err != nil {
+		return err
+	}
+
+	var roots []string
+	matchers := make(map[string]*ignore.Matcher)
+	for _, dir := range dirs {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s ist kein Ordner", dir)
+		}
+		if matchers[root], err = ignore.New(root); err != nil {
+			return err
+		}
+		roots = append(roots, root)
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("Kein Ordner zum Überwachen angegeben.")
+	}
+
+	changed := make(chan string, 256)
+	if err := startWatchBackend(ctx, roots, changed); err != nil {
+		return err
+	}
+
+	journals := make(map[string]*Journal)
+	defer func() {
+		for _, j := range journals {
+			j.Close()
+		}
+	}()
+
+	for _, root := range roots {
+		send(ctx, events, WatchEvent{Time: time.Now(), Message: "Überwache " + root})
+	}
+
+	pending := make(map[string]*pendingFile)
+	// Files we moved ourselves may land in another watched folder; they
+	// must not be picked up again.
+	ownMoves := make(map[string]time.Time)
+	seq := 0
+
+	ticker := time.NewTicker(watchTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case path := <-changed:
+			if isPartialDownload(filepath.Base(path)) || isJunkFile(filepath.Base(path)) {
+				continue
+			}
+			if _, own := ownMoves[path]; own {
+				continue
+			}
+			pending[path] = &pendingFile{size: -1}
+
+		case now := <-ticker.C:
+			for path, movedAt := range ownMoves {
+				if now.Sub(movedAt) > time.Minute {
+					delete(ownMoves, path)
+				}
+			}
+
+			// One snapshot of the open handles serves all files that
+			// settled in this tick.
+			var checker *inuse.Checker
+			for path, p := range pending {
+				info, err := os.Stat(path)
+				if err != nil || !info.Mode().IsRegular() {
+					delete(pending, path)
+					continue
+				}
+				if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+					p.size, p.modTime, p.since = info.Size(), info.ModTime(), now
+					continue
+				}
+				if now.Sub(p.since) < watchSettle {
+					continue
+				}
+				if checker == nil {
+					checker = inuse.NewChecker()
+				}
+				if reason, busy := checker.Busy(path); busy {
+					// Still open for writing: wait for another settle period.
+					if !p.busy {
+						send(ctx, events, WatchEvent{Time: now, Path: filepath.Base(path), Message: fmt.Sprintf("%s wartet (%s)", filepath.Base(path), reason)})
+						p.busy = true
+					}
+					p.since = now
+					continue
+				}
+				delete(pending, path)
+
+				root := filepath.Dir(path)
+				if matchers[root].Match(path, false) {
+					continue
+				}
+				seq++
+				event, dests := organizeWatched(ctx, root, path, info, tmpl, opts, journals, seq)
+				for _, dest := range dests {
+					ownMoves[dest] = now
+				}
+				send(ctx, events, event)
+			}
+		}
+	}
+}
+
+// organizeWatched moves a single settled file together with its sidecars
+// and returns the log entry and the absolute destinations of the files
+// moved. A sidecar whose primary file is still there waits for it.
+func organizeWatched(ctx context.Context, root, path string, info os.FileInfo, tmpl *pathTemplate, opts Options, journals map[string]*Journal, seq int) (WatchEvent, []string) {
+	event := WatchEvent{Time: time.Now(), Path: filepath.Base(path)}
+
+	if owner, ok := sidecar.Owner(path); ok {
+		event.Message = fmt.Sprintf("%s wird mit %s einsortiert", event.Path, filepath.Base(owner))
+		return event, nil
+	}
+
+	claimed := make(map[string]bool)
+	entry, err := planFile(sourceFile{Root: root, Path: path, Rel: filepath.Base(path), Info: info}, tmpl, opts, claimed, seq)
+	if err != nil {
+		event.Err = err
+		return event, nil
+	}
+	event.Category = entry.Category
+
+	if entry.Action == actionSkip {
+		event.Message = fmt.Sprintf("%s übersprungen (Name bereits vorhanden)", event.Path)
+		return event, nil
+	}
+
+	plan := []FilePreview{entry}
+	companions, _ := sidecar.Of(path)
+	for _, companion := range companions {
+		info, err := os.Stat(companion)
+		if err != nil {
+			continue
+		}
+		file, err := planFile(sourceFile{Root: root, Path: companion, Rel: filepath.Base(companion), Info: info}, tmpl, opts, map[string]bool{}, seq)
+		if err != nil {
+			event.Err = err
+			return event, nil
+		}
+		file.CompanionOf = path
+		plan = append(plan, file)
+	}
+	if err := followPrimaries(plan, opts, claimed); err != nil {
+		event.Err = err
+		return event, nil
+	}
+
+	journal, ok := journals[root]
+	if !ok {
+		journal, err = newJournal(root)
+		if err != nil {
+			event.Err = fmt.Errorf("Journal konnte nicht angelegt werden: %w", err)
+			return event, nil
+		}
+		journals[root] = journal
+	}
+
+	stats, err := executeInto(ctx, journal, plan, opts, nil)
+	if err != nil {
+		event.Err = fmt.Errorf("%s: %w", event.Path, err)
+		return event, nil
+	}
+	if len(stats.Busy) > 0 {
+		// The file is picked up again once it stops changing.
+		event.Message = fmt.Sprintf("%s übersprungen (%s)", event.Path, stats.Busy[0].Reason)
+		return event, nil
+	}
+
+	if entry.Action == actionDropIdentical {
+		event.Message = fmt.Sprintf("%s ist bereits in %s vorhanden und wurde entfernt", event.Path, entry.Category)
+		return event, nil
+	}
+	event.Dest = entry.Dest
+	if rel, err := filepath.Rel(root, entry.Dest); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		event.Dest = rel
+	}
+	if len(plan) > 1 {
+		event.Dest += fmt.Sprintf(" (+%d Begleitdateien)", len(plan)-1)
+	}
+	var dests []string
+	for _, file := range plan {
+		dests = append(dests, file.Dest)
+	}
+	return event, dests
+}
+
+func isPartialDownload(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+		return true
+	}
+	return inuse.IsPartial(name)
+}
+
+func send(ctx context.Context, events chan<- WatchEvent, event WatchEvent) {
+	select {
+	case events <- event:
+	case <-ctx.Done():
+	}
+}
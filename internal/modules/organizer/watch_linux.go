@@ -0,0 +1,73 @@
+package organizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// startWatchBackend reports files written or moved into one of roots using
+// inotify. Subfolders are not watched, so the organizer's own moves into
+// category folders never show up.
+func startWatchBackend(ctx context.Context, roots []string, changed chan<- string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+
+	dirs := make(map[int]string)
+	for _, root := range roots {
+		wd, err := syscall.InotifyAddWatch(fd, root, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO|syscall.IN_CREATE)
+		if err != nil {
+			syscall.Close(fd)
+			return os.NewSyscallError("inotify_add_watch "+root, err)
+		}
+		dirs[wd] = root
+	}
+
+	// A non-blocking descriptor lets the runtime poller wake the reader up
+	// when the file is closed.
+	f := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + syscall.SizeofInotifyEvent
+				nameEnd := nameStart + int(event.Len)
+				offset = nameEnd
+				if nameEnd > n || event.Mask&syscall.IN_ISDIR != 0 {
+					continue
+				}
+
+				name := string(buf[nameStart:nameEnd])
+				for len(name) > 0 && name[len(name)-1] == 0 {
+					name = name[:len(name)-1]
+				}
+				root, ok := dirs[int(event.Wd)]
+				if !ok || name == "" {
+					continue
+				}
+
+				select {
+				case changed <- filepath.Join(root, name):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build !linux
+
+package organizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const watchPollInterval = 2 * time.Second
+
+// startWatchBackend polls roots for new or changed files on platforms
+// without inotify support.
+func startWatchBackend(ctx context.Context, roots []string, changed chan<- string) error {
+	type state struct {
+		size    int64
+		modTime time.Time
+	}
+	seen := make(map[string]state)
+
+	scan := func(report bool) {
+		for _, root := range roots {
+			entries, err := os.ReadDir(root)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if !entry.Type().IsRegular() {
+					continue
+				}
+				info, err := entry.Info()
+				if err != nil {
+					continue
+				}
+				path := filepath.Join(root, entry.Name())
+				current := state{size: info.Size(), modTime: info.ModTime()}
+				if previous, ok := seen[path]; ok && previous == current {
+					continue
+				}
+				seen[path] = current
+				if report {
+					select {
+					case changed <- path:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}
+
+	// Files that are already there when watching starts are left alone.
+	scan(false)
+
+	go func() {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				scan(true)
+			}
+		}
+	}()
+	return nil
+}
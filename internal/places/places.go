@@ -0,0 +1,98 @@
+// Package places names coordinates after the nearest city. The list of
+// cities is embedded in the binary, so no network access is needed.
+package places
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed cities.tsv
+var citiesTSV string
+
+// earthRadius is the mean radius of the earth in kilometres.
+const earthRadius = 6371.0
+
+// Point is a position in degrees, as stored in EXIF GPS tags.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Place is a city of the embedded list.
+type Place struct {
+	City    string
+	Country string
+	Point
+}
+
+// String returns the name used for folders, e.g. "Rom, Italien".
+func (p Place) String() string {
+	return p.City + ", " + p.Country
+}
+
+var (
+	loadOnce sync.Once
+	cities   []Place
+)
+
+func load() {
+	for _, line := range strings.Split(citiesTSV, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(fields[2], 64)
+		lon, err2 := strconv.ParseFloat(fields[3], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		cities = append(cities, Place{City: fields[0], Country: fields[1], Point: Point{Lat: lat, Lon: lon}})
+	}
+}
+
+// Nearest returns the city closest to p and its distance in kilometres.
+func Nearest(p Point) (Place, float64) {
+	loadOnce.Do(load)
+
+	var best Place
+	bestDist := math.Inf(1)
+	for _, city := range cities {
+		if d := Distance(p, city.Point); d < bestDist {
+			best, bestDist = city, d
+		}
+	}
+	return best, bestDist
+}
+
+// Distance returns the great-circle distance between a and b in kilometres.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Centre returns the mean position of points. Longitudes are averaged on the
+// unit circle, so groups across the date line stay in place.
+func Centre(points []Point) Point {
+	var lat, x, y float64
+	for _, p := range points {
+		lat += p.Lat
+		x += math.Cos(radians(p.Lon))
+		y += math.Sin(radians(p.Lon))
+	}
+	n := float64(len(points))
+	return Point{Lat: lat / n, Lon: math.Atan2(y/n, x/n) * 180 / math.Pi}
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,191 @@
+// Package sidecar associates companion files with the file they belong to:
+// XMP and AAE edits next to photos and videos, the JPEG of a RAW+JPEG pair
+// and subtitles next to a film. A companion shares the base name of its
+// primary file ("IMG_0042.CR2" → "IMG_0042.xmp", "IMG_0042.CR2.xmp",
+// "Film.de.srt") and lies in the same folder. The modules that move, delete
+// or convert files treat a primary file and its companions as one unit.
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	raw       = []string{".dng", ".cr2", ".cr3", ".nef", ".arw", ".orf", ".rw2", ".raf"}
+	images    = []string{".jpg", ".jpeg", ".heic", ".png", ".tif", ".tiff"}
+	videos    = []string{".mp4", ".mov", ".mkv", ".avi", ".m4v", ".webm", ".mts"}
+	edits     = []string{".xmp", ".aae"}
+	subtitles = []string{".srt", ".vtt", ".ass", ".ssa", ".sub"}
+)
+
+// rule describes the files of one kind of primary file: its rank, where a
+// lower rank wins when several files could own a companion, and the
+// extensions of its companions.
+type rule struct {
+	rank       int
+	companions map[string]bool
+}
+
+var rules = make(map[string]rule)
+
+func init() {
+	add := func(primaries []string, rank int, companions ...[]string) {
+		set := make(map[string]bool)
+		for _, exts := range companions {
+			for _, ext := range exts {
+				set[ext] = true
+			}
+		}
+		for _, ext := range primaries {
+			rules[ext] = rule{rank: rank, companions: set}
+		}
+	}
+	// The JPEG or HEIC a camera writes next to a RAW file belongs to it.
+	add(raw, 0, edits, []string{".jpg", ".jpeg", ".heic", ".pp3", ".dop"})
+	add(images, 1, edits)
+	add(videos, 1, edits, subtitles, []string{".thm"})
+}
+
+// Split sorts paths into primary files and their companions. A file that
+// accompanies another file of the list is not returned in primaries but in
+// companions, under the path of the file it belongs to. Files with companion
+// extensions but without a primary file, such as a lone subtitle, are
+// primaries of their own.
+func Split(paths []string) (primaries []string, companions map[string][]string) {
+	// Candidates for primary files by folder and lower case base name.
+	type candidate struct {
+		path string
+		rule rule
+	}
+	byStem := make(map[string][]candidate)
+	for _, path := range paths {
+		if r, ok := rules[strings.ToLower(filepath.Ext(path))]; ok {
+			key := stemKey(path)
+			byStem[key] = append(byStem[key], candidate{path, r})
+		}
+	}
+
+	companions = make(map[string][]string)
+	for _, path := range paths {
+		ext := strings.ToLower(filepath.Ext(path))
+		owner := ""
+		best := -1
+		// "Film.de.srt" and "IMG_0042.CR2.xmp" belong to "Film" and
+		// "IMG_0042": try the base name with ever fewer dotted parts.
+		for key := stemKey(path); owner == "" && key != ""; key = trimPart(key) {
+			for _, c := range byStem[key] {
+				if c.path == path || !c.rule.companions[ext] {
+					continue
+				}
+				if owner == "" || c.rule.rank < best || (c.rule.rank == best && c.path < owner) {
+					owner, best = c.path, c.rule.rank
+				}
+			}
+		}
+		if owner == "" {
+			primaries = append(primaries, path)
+			continue
+		}
+		companions[owner] = append(companions[owner], path)
+	}
+
+	// A JPEG that belongs to a RAW file brings its own edits along. The
+	// owners are collected first, since the loop removes entries.
+	owners := make([]string, 0, len(companions))
+	for owner := range companions {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		files, ok := companions[owner]
+		if !ok {
+			continue
+		}
+		for _, file := range files {
+			if more, ok := companions[file]; ok {
+				companions[owner] = append(companions[owner], more...)
+				delete(companions, file)
+			}
+		}
+	}
+	return primaries, companions
+}
+
+// Of returns the companions of path found next to it.
+func Of(path string) ([]string, error) {
+	siblings, err := siblings(path)
+	if err != nil {
+		return nil, err
+	}
+	_, companions := Split(siblings)
+	return companions[path], nil
+}
+
+// Owner returns the primary file path belongs to, if one lies next to it.
+func Owner(path string) (string, bool) {
+	siblings, err := siblings(path)
+	if err != nil {
+		return "", false
+	}
+	_, companions := Split(siblings)
+	for owner, files := range companions {
+		for _, file := range files {
+			if file == path {
+				return owner, true
+			}
+		}
+	}
+	return "", false
+}
+
+// siblings lists the files in the folder of path.
+func siblings(path string) ([]string, error) {
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// Dest returns where companion goes when its primary file moves to
+// primaryDest: into the same folder, with the primary's new base name and
+// its own suffix, e.g. "IMG_0042 (2).xmp" for "IMG_0042 (2).CR2".
+func Dest(primary, companion, primaryDest string) string {
+	stem := strings.TrimSuffix(filepath.Base(primary), filepath.Ext(primary))
+	destName := filepath.Base(primaryDest)
+	newStem := strings.TrimSuffix(destName, filepath.Ext(destName))
+	name := filepath.Base(companion)
+	if len(name) < len(stem) || !strings.EqualFold(name[:len(stem)], stem) {
+		return filepath.Join(filepath.Dir(primaryDest), name)
+	}
+	return filepath.Join(filepath.Dir(primaryDest), newStem+name[len(stem):])
+}
+
+// stemKey identifies the base name of path within its folder, ignoring
+// case.
+func stemKey(path string) string {
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.ToLower(filepath.Join(filepath.Dir(path), stem))
+}
+
+// trimPart removes the last dotted part of a base name key, or returns ""
+// if there is none left.
+func trimPart(key string) string {
+	base := filepath.Base(key)
+	i := strings.LastIndex(base, ".")
+	if i <= 0 {
+		return ""
+	}
+	return key[:len(key)-len(base)+i]
+}
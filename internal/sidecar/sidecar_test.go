@@ -0,0 +1,86 @@
+package sidecar
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name       string
+		paths      []string
+		primaries  []string
+		companions map[string][]string
+	}{
+		{
+			name:       "RAW+JPEG mit Bearbeitungen",
+			paths:      []string{"IMG.CR2", "IMG.JPG", "img.xmp", "IMG.JPG.aae"},
+			primaries:  []string{"IMG.CR2"},
+			companions: map[string][]string{"IMG.CR2": {"IMG.JPG", "IMG.JPG.aae", "img.xmp"}},
+		},
+		{
+			name:       "Film mit Untertiteln",
+			paths:      []string{"Film.mp4", "Film.de.srt", "Film.srt"},
+			primaries:  []string{"Film.mp4"},
+			companions: map[string][]string{"Film.mp4": {"Film.de.srt", "Film.srt"}},
+		},
+		{
+			name:       "einzelner Untertitel",
+			paths:      []string{"lone.srt", "x.pdf"},
+			primaries:  []string{"lone.srt", "x.pdf"},
+			companions: map[string][]string{},
+		},
+		{
+			name:       "anderer Ordner",
+			paths:      []string{"a/IMG.jpg", "b/IMG.xmp"},
+			primaries:  []string{"a/IMG.jpg", "b/IMG.xmp"},
+			companions: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, path := range tt.paths {
+				paths = append(paths, filepath.FromSlash(path))
+			}
+			primaries, companions := Split(paths)
+			for _, files := range companions {
+				sort.Strings(files)
+			}
+			want := make(map[string][]string)
+			for owner, files := range tt.companions {
+				for _, file := range files {
+					want[filepath.FromSlash(owner)] = append(want[filepath.FromSlash(owner)], filepath.FromSlash(file))
+				}
+			}
+			var wantPrimaries []string
+			for _, path := range tt.primaries {
+				wantPrimaries = append(wantPrimaries, filepath.FromSlash(path))
+			}
+			if !reflect.DeepEqual(primaries, wantPrimaries) {
+				t.Errorf("primaries = %v, erwartet %v", primaries, wantPrimaries)
+			}
+			if !reflect.DeepEqual(companions, want) {
+				t.Errorf("companions = %v, erwartet %v", companions, want)
+			}
+		})
+	}
+}
+
+func TestDest(t *testing.T) {
+	tests := []struct {
+		primary, companion, primaryDest, want string
+	}{
+		{"a/IMG.CR2", "a/IMG.xmp", "b/IMG (2).CR2", "b/IMG (2).xmp"},
+		{"a/IMG.JPG", "a/IMG.JPG.aae", "b/Urlaub.jpg", "b/Urlaub.JPG.aae"},
+		{"a/Film.mp4", "a/Film.de.srt", "b/Film_compressed.mp4", "b/Film_compressed.de.srt"},
+	}
+	for _, tt := range tests {
+		got := Dest(filepath.FromSlash(tt.primary), filepath.FromSlash(tt.companion), filepath.FromSlash(tt.primaryDest))
+		if want := filepath.FromSlash(tt.want); got != want {
+			t.Errorf("Dest(%s, %s, %s) = %s, erwartet %s", tt.primary, tt.companion, tt.primaryDest, got, want)
+		}
+	}
+}
@@ -32,15 +32,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.organizer = organizer.New()
 				return m, tea.Batch(m.organizer.Init(), m.organizer.TextInput.Focus())
 			case 1:
+				m.state = stateOrganize
+				m.organizer = organizer.NewFlatten()
+				return m, m.organizer.TextInput.Focus()
+			case 2:
+				m.state = stateOrganize
+				m.organizer = organizer.NewWatch()
+				return m, m.organizer.TextInput.Focus()
+			case 3:
+				m.state = stateOrganize
+				m.organizer = organizer.NewHistory()
+				return m, m.organizer.Init()
+			case 4:
 				m.state = stateDeduplicate
 				m.deduplicator = deduplicator.New()
 				return m, m.deduplicator.Init()
-			case 2:
+			case 5:
 				//TODO finish compressor module
 				// m.state = stateCompress
 				// m.compressor = compressor.New()
 				// return m, m.compressor.Init()
-			case 3:
+			case 6:
 				return m, tea.Quit
 			}
 		}
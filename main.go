@@ -1,15 +1,36 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"example/ordi/internal/modules/organizer"
 	"example/ordi/internal/ui/app"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string) error{
+			"watch":      watch,
+			"links":      links,
+			"classifier": classifier,
+			"stats":      stats,
+		}
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "ordi %s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	mainModel := app.New()
 
 	p := tea.NewProgram(mainModel)
@@ -19,3 +40,195 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// watch runs the watch mode without the TUI, e.g. as a background service:
+//
+//	ordi watch [-template VORLAGE] [-conflict rename|skip|overwrite|identical] [-flat] ORDNER...
+func watch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	template := flags.String("template", organizer.DefaultTemplate, "Vorlage für die Zielpfade")
+	conflict := flags.String("conflict", "rename", "Verhalten bei Namenskonflikten: rename, skip, overwrite, identical")
+	flat := flags.Bool("flat", false, "nur Hauptkategorien, ohne Unterkategorien")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Aufruf: ordi watch [Optionen] ORDNER...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("kein Ordner angegeben")
+	}
+
+	opts := organizer.DefaultOptions()
+	taxonomy, err := organizer.LoadTaxonomy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Kategorien-Konfiguration fehlerhaft, Standardkategorien werden verwendet: %v\n", err)
+	}
+	opts.Taxonomy = taxonomy
+	if opts.Classifier, err = organizer.LoadClassifier(); err != nil {
+		fmt.Fprintf(os.Stderr, "Gelernte Kategorien konnten nicht geladen werden: %v\n", err)
+	}
+	opts.Template = *template
+	opts.FlatCategories = *flat
+	if opts.Conflict, err = organizer.ParseConflictPolicy(*conflict); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	events := make(chan organizer.WatchEvent)
+	done := make(chan error, 1)
+	go func() {
+		done <- organizer.Watch(ctx, flags.Args(), opts, events)
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			fmt.Println(event)
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+// links builds or refreshes a link tree without the TUI: links for new files
+// are added, links whose original is gone are removed.
+//
+//	ordi links [-hard] [-template VORLAGE] [-flat] QUELLE ZIEL
+func links(args []string) error {
+	flags := flag.NewFlagSet("links", flag.ContinueOnError)
+	hard := flags.Bool("hard", false, "Hardlinks statt Symlinks anlegen")
+	template := flags.String("template", organizer.DefaultTemplate, "Vorlage für die Zielpfade")
+	flat := flags.Bool("flat", false, "nur Hauptkategorien, ohne Unterkategorien")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Aufruf: ordi links [Optionen] QUELLE ZIEL")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("Quelle und Ziel müssen angegeben werden")
+	}
+
+	opts := organizer.DefaultOptions()
+	taxonomy, err := organizer.LoadTaxonomy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Kategorien-Konfiguration fehlerhaft, Standardkategorien werden verwendet: %v\n", err)
+	}
+	opts.Taxonomy = taxonomy
+	opts.Template = *template
+	opts.FlatCategories = *flat
+	opts.Mode = organizer.ModeSymlink
+	if *hard {
+		opts.Mode = organizer.ModeHardlink
+	}
+	opts.LinkTarget = flags.Arg(1)
+
+	stats, err := organizer.RefreshLinks(flags.Arg(0), opts)
+	fmt.Printf("%d Links angelegt, %d bereits vorhanden, %d verwaiste entfernt\n", stats.TotalMoved, stats.UpToDate, stats.PrunedLinks)
+	return err
+}
+
+// classifier resets or exports the categories learned from corrections in
+// the preview:
+//
+//	ordi classifier reset
+//	ordi classifier export [DATEI]
+func classifier(args []string) error {
+	usage := fmt.Errorf("Aufruf: ordi classifier reset | export [DATEI]")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "reset":
+		if err := organizer.ResetClassifier(); err != nil {
+			return err
+		}
+		fmt.Println("Gelernte Kategorien wurden zurückgesetzt.")
+		return nil
+
+	case "export":
+		c, err := organizer.LoadClassifier()
+		if err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return c.Export(os.Stdout)
+		}
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		if err := c.Export(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d Korrekturen nach %s exportiert\n", c.Size(), args[1])
+		return nil
+	}
+	return usage
+}
+
+// stats writes the size per category, the largest files and the age
+// histogram of folders as JSON, e.g. to follow a shared drive over time:
+//
+//	ordi stats [-recursive] [-depth N] [-flat] [-o DATEI] ORDNER...
+func stats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	recursive := flags.Bool("recursive", false, "Unterordner einbeziehen")
+	depth := flags.Int("depth", organizer.DefaultOptions().MaxDepth, "maximale Tiefe der Unterordner (0 = unbegrenzt)")
+	out := flags.String("o", "", "JSON in diese Datei schreiben statt auf die Standardausgabe")
+	flat := flags.Bool("flat", false, "nur Hauptkategorien, ohne Unterkategorien")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Aufruf: ordi stats [Optionen] ORDNER...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("kein Ordner angegeben")
+	}
+
+	opts := organizer.DefaultOptions()
+	taxonomy, err := organizer.LoadTaxonomy()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Kategorien-Konfiguration fehlerhaft, Standardkategorien werden verwendet: %v\n", err)
+	}
+	opts.Taxonomy = taxonomy
+	opts.Recursive = *recursive
+	opts.MaxDepth = *depth
+	opts.FlatCategories = *flat
+
+	s, err := organizer.ScanStats(flags.Args(), opts)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return s.Export(os.Stdout)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := s.Export(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Statistik über %d Dateien nach %s geschrieben\n", s.Files, *out)
+	return nil
+}